@@ -0,0 +1,51 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
+)
+
+var logoutAll bool
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the cached token of the current credentials.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if logoutAll {
+			if err := myOpenstack.PurgeTokenCache(); err != nil {
+				return fmt.Errorf("failed to purge token cache: %w", err)
+			}
+			log.Info("Removed all cached tokens")
+			return nil
+		}
+
+		if err := conf.RemoveCachedToken(); err != nil {
+			return fmt.Errorf("failed to remove cached token: %w", err)
+		}
+		log.Info("Removed cached token")
+		return nil
+	},
+}
+
+func init() {
+	authLogoutCmd.Flags().BoolVar(&logoutAll, "all", false, "Remove the cached tokens of all the credentials.")
+	authCmd.AddCommand(authLogoutCmd)
+}
@@ -0,0 +1,55 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var authTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Print a token, e.g. for the X-Auth-Token header of curl.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := commandContext()
+		defer cancel()
+
+		osClient, err := newAuthenticatedClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			info, err := osClient.TokenInfo()
+			if err != nil {
+				return fmt.Errorf("failed to read token: %w", err)
+			}
+			return printJSON(struct {
+				ID        string    `json:"id"`
+				ExpiresAt time.Time `json:"expires_at"`
+			}{osClient.Token(), info.ExpiresAt})
+		}
+
+		fmt.Println(osClient.Token())
+		return nil
+	},
+}
+
+func init() {
+	authCmd.AddCommand(authTokenCmd)
+}
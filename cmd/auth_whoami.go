@@ -0,0 +1,93 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
+)
+
+var authWhoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the user, scope, roles and expiry of the token.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := commandContext()
+		defer cancel()
+
+		osClient, err := newAuthenticatedClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		info, err := osClient.TokenInfo()
+		if err != nil {
+			return fmt.Errorf("failed to read token: %w", err)
+		}
+
+		if outputFormat == "json" {
+			return printJSON(info)
+		}
+
+		fmt.Printf("User: %s (%s), domain: %s\n", info.User.Name, info.User.ID, info.User.Domain.Name)
+		switch {
+		case info.Project != nil:
+			fmt.Printf("Project: %s (%s), domain: %s\n", info.Project.Name, info.Project.ID, info.Project.Domain.Name)
+		case info.Domain != nil:
+			fmt.Printf("Domain: %s (%s)\n", info.Domain.Name, info.Domain.ID)
+		case len(info.System) > 0:
+			fmt.Println("System: all")
+		default:
+			fmt.Println("Unscoped")
+		}
+
+		var roles []string
+		for _, role := range info.Roles {
+			roles = append(roles, role.Name)
+		}
+		fmt.Printf("Roles: %s\n", strings.Join(roles, ", "))
+		fmt.Printf("Expires at: %s (in %s)\n", info.ExpiresAt.Local().Format(time.RFC3339), time.Until(info.ExpiresAt).Round(time.Second))
+		return nil
+	},
+}
+
+// newAuthenticatedClient returns the client of the current credentials. The fields identifying the credentials are
+// logged if the authentication fails.
+func newAuthenticatedClient(ctx context.Context) (*myOpenstack.OpenStack, error) {
+	osClient, err := myOpenstack.NewOpenStack(ctx, conf)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"cloud":        conf.Cloud,
+			"auth_url":     conf.AuthURL,
+			"user":         conf.Username,
+			"project_name": conf.ProjectName,
+			"project_id":   conf.ProjectID,
+			"region":       conf.Region,
+		}).Debug("Failed to authenticate")
+		return nil, fmt.Errorf("failed to initialize openstack client: %w", err)
+	}
+	return osClient, nil
+}
+
+func init() {
+	authCmd.AddCommand(authWhoamiCmd)
+}
@@ -0,0 +1,32 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the contexts in the osctl config file.",
+	// The config commands only deal with the config file, credentials are not needed.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return startCommand(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
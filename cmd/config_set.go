@@ -0,0 +1,78 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/lingxiankong/openstackcli-go/pkg/config"
+)
+
+var configSetCmd = &cobra.Command{
+	Use:   "set NAME KEY=VALUE...",
+	Short: "Create or update a context in the osctl config file.",
+	Long: `Create or update a context in the osctl config file. The supported keys are:
+	- cloud: the cloud name in clouds.yaml that provides the credentials.
+	- region: the region name.
+	- interface: the endpoint interface, public, internal or admin.
+	- endpoints.<service>: the endpoint used instead of the catalog one, service is one of octavia, nova, neutron,
+	  glance and keystone.
+	- project: the default project ID used to filter resources.
+	- output: the output format of the get and auth commands, text or json.
+	- password_command: the command run by the shell to get the password, e.g. "pass show openstack/prod".
+
+The first context created becomes the current context. Set an empty value to unset a key, e.g. "region=".`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, path, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+
+		ctx := config.Context{Name: args[0]}
+		if existing := c.Context(args[0]); existing != nil {
+			ctx = *existing
+		}
+
+		for _, kv := range args[1:] {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 {
+				return &usageError{fmt.Errorf("invalid argument %q, KEY=VALUE expected", kv)}
+			}
+			if err := ctx.Set(parts[0], parts[1]); err != nil {
+				return &usageError{fmt.Errorf("failed to set context: %w", err)}
+			}
+		}
+
+		c.SetContext(ctx)
+		if c.CurrentContext == "" {
+			c.CurrentContext = ctx.Name
+		}
+
+		if err := c.Save(path); err != nil {
+			return fmt.Errorf("failed to save config file %s: %w", path, err)
+		}
+		log.WithFields(log.Fields{"context": ctx.Name}).Info("Context saved")
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configSetCmd)
+}
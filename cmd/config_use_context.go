@@ -0,0 +1,49 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var configUseContextCmd = &cobra.Command{
+	Use:   "use-context NAME",
+	Short: "Set the current context in the osctl config file.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, path, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+
+		if c.Context(args[0]) == nil {
+			return &usageError{fmt.Errorf("context %s not found in %s", args[0], path)}
+		}
+		c.CurrentContext = args[0]
+
+		if err := c.Save(path); err != nil {
+			return fmt.Errorf("failed to save config file %s: %w", path, err)
+		}
+		log.WithFields(log.Fields{"context": args[0]}).Info("Switched context")
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configUseContextCmd)
+}
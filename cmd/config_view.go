@@ -0,0 +1,45 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Show the osctl config file.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, _, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("failed to render config: %w", err)
+		}
+		fmt.Print(string(data))
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configViewCmd)
+}
@@ -0,0 +1,78 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/lingxiankong/openstackcli-go/pkg/fakecloud"
+)
+
+var (
+	fixtureFile  string
+	listenAddr   string
+	printFixture bool
+)
+
+var devFakeCloudCmd = &cobra.Command{
+	Use:   "fake-cloud",
+	Short: "Serve a fake OpenStack cloud seeded from a YAML fixture for offline testing and demos.",
+	Long: `Serve the subset of the keystone, octavia, nova, neutron and glance APIs used by osctl.
+
+The load balancers go to PENDING_UPDATE after a failover and back to ACTIVE with amphorae running the latest
+amphora image. The fixture can also inject errors and latency into the matching requests. Use --print-fixture to
+get the built-in fixture as a starting point.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if printFixture {
+			fmt.Print(fakecloud.DefaultFixtureYAML())
+			return nil
+		}
+
+		fixture := fakecloud.DefaultFixture()
+		if fixtureFile != "" {
+			var err error
+			if fixture, err = fakecloud.LoadFixture(fixtureFile); err != nil {
+				return fmt.Errorf("failed to load fixture %s: %w", fixtureFile, err)
+			}
+		}
+
+		l, err := net.Listen("tcp", listenAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+
+		authURL := fakecloud.AuthURL("http://" + l.Addr().String())
+		log.WithFields(log.Fields{"auth_url": authURL}).Info("Fake cloud is running, e.g. osctl get loadbalancers -a " + authURL +
+			" -u admin -p password --project-name admin")
+
+		if err := http.Serve(l, fakecloud.New(fixture)); err != nil {
+			return fmt.Errorf("fake cloud stopped: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	devFakeCloudCmd.Flags().StringVar(&fixtureFile, "fixture", "", "YAML fixture of the users, projects, resources and faults (default is the built-in fixture)")
+	devFakeCloudCmd.Flags().StringVar(&listenAddr, "listen", "127.0.0.1:8770", "address to listen on")
+	devFakeCloudCmd.Flags().BoolVar(&printFixture, "print-fixture", false, "print the built-in fixture and exit")
+	devCmd.AddCommand(devFakeCloudCmd)
+}
@@ -0,0 +1,102 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
+)
+
+// The exit codes of osctl, see exitCodesHelp.
+const (
+	exitError       = 1
+	exitUsage       = 2
+	exitAuth        = 3
+	exitNotFound    = 4
+	exitConflict    = 5
+	exitTimeout     = 6
+	exitQuota       = 7
+	exitPartial     = 8
+	exitInterrupted = 130
+)
+
+// exitCodesHelp documents the exit codes for the wrapper scripts.
+const exitCodesHelp = `Exit codes:
+  0    success
+  1    error not covered below
+  2    invalid command line, e.g. unknown flag, wrong arguments or invalid flag value
+  3    authentication or authorization failed
+  4    resource not found
+  5    conflict, e.g. the resource is in an immutable state
+  6    timed out
+  7    quota exceeded
+  8    partial failure, the command failed in some of the clouds and regions, or for some of the resources
+  130  interrupted`
+
+// commandStarted is set once the command line is parsed and validated, the errors before are usage errors.
+var commandStarted bool
+
+// startCommand marks the command as started and sets up the logs. The errors from now on are not caused by the command
+// line, so the usage isn't printed, and Execute logs them instead of cobra printing them.
+func startCommand(cmd *cobra.Command) error {
+	commandStarted = true
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return setupLogging()
+}
+
+// usageError is an invalid flag value or argument found by the command itself.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// exitCode returns the exit code for the error of the command.
+func exitCode(err error) int {
+	var ue *usageError
+	switch {
+	case err == nil:
+		return 0
+	case !commandStarted || errors.As(err, &ue):
+		return exitUsage
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	// A partial failure wraps the error of the first failed target, so it's checked before the other classes.
+	case errors.Is(err, myOpenstack.ErrPartial):
+		return exitPartial
+	case errors.Is(err, myOpenstack.ErrAuth):
+		return exitAuth
+	case errors.Is(err, myOpenstack.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, myOpenstack.ErrConflict):
+		return exitConflict
+	case errors.Is(err, myOpenstack.ErrTimeout) || errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case errors.Is(err, myOpenstack.ErrQuota):
+		return exitQuota
+	}
+	return exitError
+}
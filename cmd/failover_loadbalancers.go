@@ -17,16 +17,15 @@ package cmd
 import (
 	"context"
 	"errors"
-	"os"
-	"os/signal"
+	"fmt"
 	"strings"
 	"sync"
-	"syscall"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
+	"github.com/lingxiankong/openstackcli-go/pkg/tracing"
 	"github.com/lingxiankong/openstackcli-go/pkg/util"
 )
 
@@ -52,71 +51,64 @@ var failoverLoadBalancersCmd = &cobra.Command{
 		}
 		return nil
 	},
-	Run: func(cmd *cobra.Command, args []string) {
-		osClient, err := myOpenstack.NewOpenStack(conf)
-		if err != nil {
-			log.WithFields(log.Fields{"error": err}).Fatal("Failed to initialize openstack client")
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := commandContext()
+		defer cancel()
 
-		// Get the latest amphora image
-		imageID, err := osClient.GetAmphoraImage()
+		osClient, err := myOpenstack.NewOpenStack(ctx, conf)
 		if err != nil {
-			log.Fatalf("Failed to get latest amphora image: %v", err)
+			return fmt.Errorf("failed to initialize openstack client: %w", err)
 		}
 
-		lbs, err := osClient.GetLoadbalancers(projectID)
+		clients, err := regionClients(osClient, []string{"octavia", "nova", "glance"})
 		if err != nil {
-			log.WithFields(log.Fields{"error": err}).Fatal("Failed to get load balancers.")
+			return fmt.Errorf("failed to get regions: %w", err)
 		}
 
-		var validLBs []string
-
-		// Find LBs that can be failed over, for LBs in invalid status, show the updated timestamp and skip.
-		for _, lb := range lbs {
-			if strings.Contains(lb.Name, "tempest") {
-				log.WithFields(log.Fields{"loadbalancer": lb.ID}).Info("Created by tempest, skip")
-				continue
-			}
-
-			if len(excludeLBs) > 0 && util.FindString(lb.ID, excludeLBs) {
-				log.WithFields(log.Fields{"loadbalancer": lb.ID}).Info("excluded")
-				continue
-			}
-
-			if len(includeLBs) == 0 || util.FindString(lb.ID, includeLBs) {
-				if lb.ProvisioningStatus != "ACTIVE" && lb.ProvisioningStatus != "ERROR" {
-					log.WithFields(log.Fields{"loadbalancer": lb.ID}).Warnf("Load balancer %s not in ACTIVE or ERROR, updated at %s, skipped", lb.Name, lb.UpdatedAt)
-				} else {
-					validLBs = append(validLBs, lb.ID)
+		// errs are the errors of the failed regions and load balancers.
+		var errs []error
+		var validLBs []failoverTarget
+		for _, c := range clients {
+			candidates, err := failoverCandidates(ctx, c)
+			if err != nil {
+				// A failure in one of several regions doesn't stop the failover in the others.
+				if !multiRegion() || ctx.Err() != nil {
+					return err
 				}
+				log.WithFields(log.Fields{"region": c.Region(), "error": err}).Error("Region failed, skipped")
+				errs = append(errs, err)
+				continue
 			}
+			validLBs = append(validLBs, candidates...)
 		}
 
 		if len(validLBs) == 0 {
+			if err := myOpenstack.CombineErrors(len(clients), errs); err != nil {
+				return fmt.Errorf("failed to failover load balancers: %w", err)
+			}
 			log.Info("No load balancers need to failover.")
-			return
+			return nil
 		}
 
-		log.WithFields(log.Fields{"loadbalancers": validLBs}).Infof("Will failover %d load balancers.", len(validLBs))
+		var lbIDs []string
+		for _, t := range validLBs {
+			lbIDs = append(lbIDs, t.lbID)
+		}
+		log.WithFields(log.Fields{"loadbalancers": lbIDs}).Infof("Will failover %d load balancers.", len(validLBs))
 
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+		// A failure stops the workers from taking more load balancers, the ongoing failovers are only interrupted by a
+		// signal.
+		stopCtx, stop := context.WithCancel(ctx)
+		defer stop()
 
-		lbsCh := make(chan string)
+		lbsCh := make(chan failoverTarget)
 		failCh := make(chan bool, parallelism)
 		var waitgroup sync.WaitGroup
-
-		// Catch signals
-		sigs := make(chan os.Signal, 1)
-		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-		go func() {
-			<-sigs
-			cancel()
-			log.Info("Waiting for the existing operation to be finished...")
-		}()
+		var mu sync.Mutex
+		var finished int
 
 		// Fill the lbs need to failover into a channel
-		go func(ch chan string, lbs []string) {
+		go func(ch chan failoverTarget, lbs []failoverTarget) {
 			for _, lb := range lbs {
 				ch <- lb
 			}
@@ -126,42 +118,113 @@ var failoverLoadBalancersCmd = &cobra.Command{
 		// Create parallelism goroutines to handle all the lbs. If any of the goroutines fails, the whole process will stop.
 		for i := 0; i < parallelism; i++ {
 			waitgroup.Add(1)
-			go func(ctx context.Context, ch <-chan string, failCh chan<- bool) {
+			go func(worker int, stopCtx context.Context, ch <-chan failoverTarget, failCh chan<- bool) {
 				defer waitgroup.Done()
+				ctx, span := tracing.Start(ctx, "failover worker", tracing.Int("worker", worker))
+				defer span.End()
 
 				for {
 					select {
-					case lbID, ok := <-ch:
+					case t, ok := <-ch:
 						if !ok {
 							return
 						}
 
-						log.WithFields(log.Fields{"loadbalancer": lbID}).Info("Starting failover load balancer")
-
-						if err := osClient.FailoverLoadBalancer(lbID, imageID, timeout); err != nil {
-							log.WithFields(log.Fields{"loadbalancer": lbID}).Errorf("Failed to failover load balancer: %v", err)
+						logger := log.WithFields(log.Fields{"loadbalancer": t.lbID, "region": t.client.Region()})
+						logger.Info("Starting failover load balancer")
+
+						if err := t.client.FailoverLoadBalancer(ctx, t.lbID, t.imageID, timeout); err != nil {
+							if ctx.Err() != nil {
+								logger.WithFields(log.Fields{"error": err}).Warn("Failover load balancer interrupted")
+								return
+							}
+							logger.WithFields(log.Fields{"error": err}).Error("Failed to failover load balancer")
+							mu.Lock()
+							errs = append(errs, fmt.Errorf("load balancer %s: %w", t.lbID, err))
+							mu.Unlock()
 							failCh <- true
 							return
 						} else {
-							log.WithFields(log.Fields{"loadbalancer": lbID}).Info("Finished to failover load balancer")
+							logger.Info("Finished to failover load balancer")
+							mu.Lock()
+							finished++
+							mu.Unlock()
 						}
-					case <-ctx.Done():
+					case <-stopCtx.Done():
 						return
 					}
 				}
-			}(ctx, lbsCh, failCh)
+			}(i, stopCtx, lbsCh, failCh)
 		}
 
 		go func(failCh chan bool) {
 			if <-failCh {
-				cancel()
+				stop()
 			}
 		}(failCh)
 
 		waitgroup.Wait()
+
+		if ctx.Err() != nil {
+			return fmt.Errorf("failover interrupted after %d of %d load balancers: %w", finished, len(validLBs), ctx.Err())
+		}
+		// The load balancers not started after a failure don't count, the failed regions do. The failover is partial if
+		// any load balancer finished.
+		if err := myOpenstack.CombineErrors(finished+len(errs), errs); err != nil {
+			return fmt.Errorf("failed to failover load balancers: %w", err)
+		}
+		return nil
 	},
 }
 
+// failoverTarget is a load balancer to fail over and the client of its region.
+type failoverTarget struct {
+	client  *myOpenstack.OpenStack
+	lbID    string
+	imageID string
+}
+
+// failoverCandidates finds the load balancers that can be failed over in the region of the client. For load balancers
+// in invalid status, show the updated timestamp and skip.
+func failoverCandidates(ctx context.Context, osClient *myOpenstack.OpenStack) ([]failoverTarget, error) {
+	logger := log.WithFields(log.Fields{"region": osClient.Region()})
+
+	// Get the latest amphora image
+	imageID, err := osClient.GetAmphoraImage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest amphora image in region %s: %w", osClient.Region(), err)
+	}
+
+	lbs, err := osClient.GetLoadbalancers(ctx, projectID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get load balancers in region %s: %w", osClient.Region(), err)
+	}
+
+	var validLBs []failoverTarget
+
+	for _, lb := range lbs {
+		if strings.Contains(lb.Name, "tempest") {
+			logger.WithFields(log.Fields{"loadbalancer": lb.ID}).Info("Created by tempest, skip")
+			continue
+		}
+
+		if len(excludeLBs) > 0 && util.FindString(lb.ID, excludeLBs) {
+			logger.WithFields(log.Fields{"loadbalancer": lb.ID}).Info("excluded")
+			continue
+		}
+
+		if len(includeLBs) == 0 || util.FindString(lb.ID, includeLBs) {
+			if lb.ProvisioningStatus != "ACTIVE" && lb.ProvisioningStatus != "ERROR" {
+				logger.WithFields(log.Fields{"loadbalancer": lb.ID}).Warnf("Load balancer %s not in ACTIVE or ERROR, updated at %s, skipped", lb.Name, lb.UpdatedAt)
+			} else {
+				validLBs = append(validLBs, failoverTarget{client: osClient, lbID: lb.ID, imageID: imageID})
+			}
+		}
+	}
+
+	return validLBs, nil
+}
+
 func init() {
 	failoverLoadBalancersCmd.Flags().IntVar(&parallelism, "parallelism", 2, "Specifies the maximum desired number(1-5) of failover processes at any given time.")
 	failoverLoadBalancersCmd.Flags().StringVar(&projectID, "project", "", "Only do failover for the load balancers belonging to the given project.")
@@ -169,5 +232,6 @@ func init() {
 	failoverLoadBalancersCmd.Flags().StringSliceVarP(&includeLBs, "include-loadbalancers", "i", nil, "Load balancer IDs to include.")
 	failoverLoadBalancersCmd.Flags().IntVarP(&timeout, "timeout", "t", 600, "Timeout in seconds for the failover process.")
 
+	addRegionFlags(failoverLoadBalancersCmd)
 	failoverCmd.AddCommand(failoverLoadBalancersCmd)
 }
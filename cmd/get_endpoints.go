@@ -0,0 +1,109 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
+)
+
+// catalogEndpoint is an endpoint in the service catalog.
+type catalogEndpoint struct {
+	Cloud     string `json:"cloud,omitempty"`
+	Service   string `json:"service"`
+	Type      string `json:"type"`
+	Interface string `json:"interface"`
+	Region    string `json:"region"`
+	URL       string `json:"url"`
+}
+
+var getEndpointsCmd = &cobra.Command{
+	Use:   "endpoints",
+	Short: "Get the endpoints in the service catalog of the token, filtered by region and interface.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := commandContext()
+		defer cancel()
+
+		var mu sync.Mutex
+		var all []catalogEndpoint
+
+		err := forEachTarget(ctx, nil, func(ctx context.Context, p *rowPrinter, osClient *myOpenstack.OpenStack) error {
+			entries, err := osClient.Catalog()
+			if err != nil {
+				return fmt.Errorf("failed to get service catalog: %w", err)
+			}
+
+			var cloud string
+			if multiCloud() {
+				cloud = osClient.Cloud()
+			}
+
+			var rows []catalogEndpoint
+			for _, entry := range entries {
+				for _, endpoint := range entry.Endpoints {
+					rows = append(rows, catalogEndpoint{
+						Cloud:     cloud,
+						Service:   entry.Name,
+						Type:      entry.Type,
+						Interface: endpoint.Interface,
+						Region:    myOpenstack.EndpointRegion(endpoint),
+						URL:       endpoint.URL,
+					})
+				}
+			}
+
+			if outputFormat == "json" {
+				mu.Lock()
+				defer mu.Unlock()
+				all = append(all, rows...)
+				return nil
+			}
+
+			for _, row := range rows {
+				if multiCloud() {
+					fmt.Fprintf(p.w, "Cloud: %s, ", row.Cloud)
+				}
+				fmt.Fprintf(p.w, "Service: %s, Type: %s, Interface: %s, Region: %s, URL: %s\n", row.Service, row.Type, row.Interface, row.Region, row.URL)
+			}
+			return nil
+		})
+
+		if outputFormat == "json" {
+			sort.SliceStable(all, func(i, j int) bool {
+				if all[i].Cloud != all[j].Cloud {
+					return all[i].Cloud < all[j].Cloud
+				}
+				return all[i].Region < all[j].Region
+			})
+			if jsonErr := printJSON(all); jsonErr != nil {
+				return jsonErr
+			}
+		}
+		return err
+	},
+}
+
+func init() {
+	addRegionFlags(getEndpointsCmd)
+	addCloudFlags(getEndpointsCmd)
+	getCmd.AddCommand(getEndpointsCmd)
+}
@@ -15,12 +15,14 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
-	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/servergroups"
 	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
-	"github.com/gophercloud/gophercloud/pagination"
-	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
@@ -31,71 +33,163 @@ var lbID string
 var getLoadBalancerCmd = &cobra.Command{
 	Use:   "loadbalancer",
 	Short: "Get all the underlying resources related to the load balancer(admin only)",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		lbID = args[0]
-		osClient, err := myOpenstack.NewOpenStack(conf)
-		if err != nil {
-			log.WithFields(log.Fields{"error": err}).Fatal("Failed to initialize openstack client")
-		}
-
-		// vip
-		lb, err := loadbalancers.Get(osClient.Octavia, lbID).Extract()
-		if err != nil {
-			log.WithFields(log.Fields{"error": err, "lbID": lbID}).Fatal("Failed to get the loadbalancer info")
-		}
-		fmt.Println(fmt.Sprintf("vip port: %s, IP: %s", lb.VipPortID, lb.VipAddress))
+	Long: `Get all the underlying resources related to the load balancer(admin only).
 
-		// vip sg
-		vipSgs, err := osClient.GetPortSecurityGroups(lb.VipPortID)
-		if err != nil {
-			log.WithFields(log.Fields{"error": err, "portID": lb.VipPortID}).Fatal("Failed to get vip port security groups")
-		}
-		fmt.Println(fmt.Sprintf("\tsecurity groups: %s", vipSgs))
+The json output is a list with the resources in each cloud and region the load balancer is found in.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := commandContext()
+		defer cancel()
 
-		// server group
-		expectedName := fmt.Sprintf("octavia-lb-%s", lb.Name)
-		err = servergroups.List(osClient.Nova).EachPage(func(page pagination.Page) (bool, error) {
-			actual, err := servergroups.ExtractServerGroups(page)
+		lbID = args[0]
+		// The load balancer is only expected in one of the clouds and regions.
+		var mu sync.Mutex
+		var found []*loadBalancerResources
+		err := forEachTarget(ctx, []string{"octavia", "neutron", "nova"}, func(ctx context.Context, p *rowPrinter, c *myOpenstack.OpenStack) error {
+			lb, err := c.GetLoadBalancer(ctx, lbID)
 			if err != nil {
-				return false, err
+				if errors.Is(err, myOpenstack.ErrNotFound) && (multiRegion() || multiCloud()) {
+					return nil
+				}
+				return fmt.Errorf("failed to get the loadbalancer info: %w", err)
 			}
 
-			for _, sg := range actual {
-				if sg.Name == expectedName {
-					fmt.Println(fmt.Sprintf("server group: %s", sg.ID))
-					// return false to stop iteration.
-					return false, nil
-				}
+			res, err := getLoadBalancerResources(ctx, c, lb)
+			if err != nil {
+				return err
 			}
+			mu.Lock()
+			found = append(found, res)
+			mu.Unlock()
 
-			return true, nil
+			if outputFormat != "json" {
+				printLoadBalancerResources(p, res)
+			}
+			return nil
 		})
+
+		if outputFormat == "json" && len(found) > 0 {
+			sort.SliceStable(found, func(i, j int) bool {
+				if found[i].Cloud != found[j].Cloud {
+					return found[i].Cloud < found[j].Cloud
+				}
+				return found[i].Region < found[j].Region
+			})
+			if jsonErr := printJSON(found); jsonErr != nil {
+				return jsonErr
+			}
+		}
 		if err != nil {
-			log.WithFields(log.Fields{"error": err}).Fatal("Failed to query server group")
+			return err
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("load balancer %s %w", lbID, myOpenstack.ErrNotFound)
+		}
+		return nil
+	},
+}
+
+// loadBalancerResources are the underlying resources of a load balancer. The cloud and region are only set when
+// running across clouds or regions.
+type loadBalancerResources struct {
+	Cloud             string             `json:"cloud,omitempty"`
+	Region            string             `json:"region,omitempty"`
+	ID                string             `json:"id"`
+	VipPortID         string             `json:"vip_port_id"`
+	VipAddress        string             `json:"vip_address"`
+	VipSecurityGroups []string           `json:"vip_security_groups"`
+	ServerGroupID     string             `json:"server_group_id,omitempty"`
+	Amphorae          []amphoraResources `json:"amphorae"`
+}
+
+type amphoraResources struct {
+	ComputeID          string     `json:"compute_id"`
+	Role               string     `json:"role,omitempty"`
+	CertExpiration     *time.Time `json:"cert_expiration,omitempty"`
+	VRRPPortID         string     `json:"vrrp_port_id"`
+	VRRPSecurityGroups []string   `json:"vrrp_security_groups"`
+}
+
+// getLoadBalancerResources gets the underlying resources of the load balancer.
+func getLoadBalancerResources(ctx context.Context, osClient *myOpenstack.OpenStack, lb *loadbalancers.LoadBalancer) (*loadBalancerResources, error) {
+	res := &loadBalancerResources{ID: lb.ID, VipPortID: lb.VipPortID, VipAddress: lb.VipAddress}
+	if multiCloud() {
+		res.Cloud = osClient.Cloud()
+	}
+	if multiRegion() {
+		res.Region = osClient.Region()
+	}
+
+	// vip sg
+	vipSgs, err := osClient.GetPortSecurityGroups(ctx, lb.VipPortID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vip port %s security groups: %w", lb.VipPortID, err)
+	}
+	res.VipSecurityGroups = vipSgs
+
+	// server group
+	expectedName := fmt.Sprintf("octavia-lb-%s", lb.Name)
+	sg, err := osClient.GetServerGroupByName(ctx, expectedName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query server group: %w", err)
+	}
+	if sg != nil {
+		res.ServerGroupID = sg.ID
+	}
+
+	// amphorae
+	ams, err := osClient.GetLoadBalancerAmphorae(ctx, lb.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get amphorae: %w", err)
+	}
+
+	res.Amphorae = make([]amphoraResources, 0, len(ams))
+	for _, am := range ams {
+		amphora := amphoraResources{ComputeID: am.ComputeID, Role: am.Role, VRRPPortID: am.VRRPPortID}
+		if !am.CertExpiration.IsZero() {
+			expiration := am.CertExpiration
+			amphora.CertExpiration = &expiration
 		}
 
-		// amphorae
-		ams, err := osClient.GetLoadBalancerAmphorae(lbID)
+		// vrrp port sg
+		sgs, err := osClient.GetPortSecurityGroups(ctx, am.VRRPPortID)
 		if err != nil {
-			log.WithFields(log.Fields{"error": err, "lbID": lbID}).Fatal("Failed to get amphorae")
+			return nil, fmt.Errorf("failed to get vrrp port %s security groups: %w", am.VRRPPortID, err)
 		}
+		amphora.VRRPSecurityGroups = sgs
 
-		fmt.Println("amphorae:")
-		for _, am := range ams {
-			fmt.Println(fmt.Sprintf("\t%s", am.ComputeID))
-			fmt.Println(fmt.Sprintf("\t\tvrrp port: %s", am.VRRPPortID))
+		res.Amphorae = append(res.Amphorae, amphora)
+	}
 
-			// vrrp port sg
-			sgs, err := osClient.GetPortSecurityGroups(am.VRRPPortID)
-			if err != nil {
-				log.WithFields(log.Fields{"error": err, "portID": am.VRRPPortID}).Fatal("Failed to get vrrp port security groups")
-			}
-			fmt.Println(fmt.Sprintf("\t\t\tsecurity groups: %s", sgs))
+	return res, nil
+}
+
+// printLoadBalancerResources prints the underlying resources of the load balancer as text.
+func printLoadBalancerResources(p *rowPrinter, res *loadBalancerResources) {
+	p.Println(fmt.Sprintf("vip port: %s, IP: %s", res.VipPortID, res.VipAddress))
+	p.Println(fmt.Sprintf("\tsecurity groups: %s", res.VipSecurityGroups))
+
+	if res.ServerGroupID != "" {
+		p.Println(fmt.Sprintf("server group: %s", res.ServerGroupID))
+	}
+
+	p.Println("amphorae:")
+	for _, am := range res.Amphorae {
+		amLine := fmt.Sprintf("\t%s", am.ComputeID)
+		if am.Role != "" {
+			amLine += fmt.Sprintf(", role: %s", am.Role)
 		}
-	},
+		if am.CertExpiration != nil {
+			amLine += fmt.Sprintf(", cert expiration: %s", am.CertExpiration.Format(time.RFC3339))
+		}
+		p.Println(amLine)
+		p.Println(fmt.Sprintf("\t\tvrrp port: %s", am.VRRPPortID))
+		p.Println(fmt.Sprintf("\t\t\tsecurity groups: %s", am.VRRPSecurityGroups))
+	}
 }
 
 func init() {
+	addRegionFlags(getLoadBalancerCmd)
+	addCloudFlags(getLoadBalancerCmd)
 	getCmd.AddCommand(getLoadBalancerCmd)
 }
@@ -0,0 +1,88 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lingxiankong/openstackcli-go/pkg/fakecloud"
+	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
+)
+
+// newFakeCloudClient serves the default fixture and returns the client of its admin user. The server has to be closed
+// by the caller.
+func newFakeCloudClient(t *testing.T) (*httptest.Server, *myOpenstack.OpenStack) {
+	t.Helper()
+
+	server := httptest.NewServer(fakecloud.New(fakecloud.DefaultFixture()))
+	osClient, err := myOpenstack.NewOpenStack(context.Background(), myOpenstack.OpenStackConfig{
+		AuthURL:     fakecloud.AuthURL(server.URL),
+		Username:    "admin",
+		Password:    "password",
+		ProjectName: "admin",
+		Region:      "RegionOne",
+	})
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return server, osClient
+}
+
+func TestGetLoadBalancerResources(t *testing.T) {
+	server, osClient := newFakeCloudClient(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	lb, err := osClient.GetLoadBalancer(ctx, "lb-web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := getLoadBalancerResources(ctx, osClient, lb)
+	if err != nil {
+		t.Fatalf("getLoadBalancerResources() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	printLoadBalancerResources(&rowPrinter{w: &buf, prefix: "[RegionOne] "}, res)
+	want := `[RegionOne] vip port: port-vip-lb-web, IP: 10.0.0.10
+[RegionOne] 	security groups: [sg-lb-mgmt]
+[RegionOne] amphorae:
+[RegionOne] 	vm-amp-web-1, role: MASTER, cert expiration: 2022-01-01T00:00:00Z
+[RegionOne] 		vrrp port: port-vrrp-amp-web-1
+[RegionOne] 			security groups: [sg-lb-mgmt]
+[RegionOne] 	vm-amp-web-2, role: BACKUP, cert expiration: 2022-01-01T00:00:00Z
+[RegionOne] 		vrrp port: port-vrrp-amp-web-2
+[RegionOne] 			security groups: [sg-lb-mgmt]
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printLoadBalancerResources() printed\n%s\nwant\n%s", got, want)
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{"id":"lb-web","vip_port_id":"port-vip-lb-web","vip_address":"10.0.0.10","vip_security_groups":["sg-lb-mgmt"],"amphorae":[` +
+		`{"compute_id":"vm-amp-web-1","role":"MASTER","cert_expiration":"2022-01-01T00:00:00Z","vrrp_port_id":"port-vrrp-amp-web-1","vrrp_security_groups":["sg-lb-mgmt"]},` +
+		`{"compute_id":"vm-amp-web-2","role":"BACKUP","cert_expiration":"2022-01-01T00:00:00Z","vrrp_port_id":"port-vrrp-amp-web-2","vrrp_security_groups":["sg-lb-mgmt"]}]}`
+	if string(data) != wantJSON {
+		t.Errorf("getLoadBalancerResources() in json = %s, want %s", data, wantJSON)
+	}
+}
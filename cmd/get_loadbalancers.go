@@ -15,97 +15,228 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
-	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/listeners"
-	log "github.com/sirupsen/logrus"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/pools"
 	"github.com/spf13/cobra"
 
 	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
 )
 
-var projectID string
+var (
+	projectID string
+	lbTags    []string
+)
 
 var getLoadBalancersCmd = &cobra.Command{
 	Use:   "loadbalancers",
 	Short: "Get all the load balancers and the sub-resources(listeners, pools, members, etc.).",
-	Run: func(cmd *cobra.Command, args []string) {
-		osClient, err := myOpenstack.NewOpenStack(conf)
-		if err != nil {
-			log.WithFields(log.Fields{"error": err}).Fatal("Failed to initialize openstack client")
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := commandContext()
+		defer cancel()
 
-		lbs, err := osClient.GetLoadbalancers(projectID)
-		if err != nil {
-			log.WithFields(log.Fields{"error": err}).Fatal("Failed to get load balancers.")
-		}
+		var mu sync.Mutex
+		var all []loadBalancerTree
 
-		for _, lb := range lbs {
-			var lbInfoList []string
-			lbInfoList = append(lbInfoList, fmt.Sprintf("- LoadBalancer: %s", lb.ID), fmt.Sprintf("status: %s", lb.ProvisioningStatus), fmt.Sprintf("vip: %s", lb.VipAddress))
-			if lb.Name != "" {
-				lbInfoList = append(lbInfoList, fmt.Sprintf("name: %s", lb.Name))
+		err := forEachTarget(ctx, []string{"octavia"}, func(ctx context.Context, p *rowPrinter, osClient *myOpenstack.OpenStack) error {
+			lbs, err := getLoadBalancerTrees(ctx, osClient)
+			if err != nil {
+				return err
 			}
-			fmt.Println(strings.Join(lbInfoList, ", "))
 
-			for _, listener := range lb.Listeners {
-				listenerInfo, err := listeners.Get(osClient.Octavia, listener.ID).Extract()
-				if err != nil {
-					log.WithFields(log.Fields{"error": err, "loadbalancer": lb.ID, "listener": listener.ID}).Fatal("Failed to get listener.")
-				}
+			if outputFormat == "json" {
+				mu.Lock()
+				defer mu.Unlock()
+				all = append(all, lbs...)
+				return nil
+			}
 
-				listenerLine := fmt.Sprintf("\t- Listener: %s, protocol: %s, port: %d", listenerInfo.ID, listenerInfo.Protocol, listenerInfo.ProtocolPort)
-				if listenerInfo.Name != "" {
-					listenerLine += fmt.Sprintf(", name: %s", listenerInfo.Name)
-				}
-				fmt.Println(listenerLine)
+			printLoadBalancers(p, lbs)
+			return nil
+		})
 
-				// Get listener pools, pools can only be retrieved by loadbalancer rather than listener.
-				listenerPools, err := osClient.GetPools(lb.ID, false, listener.ID)
-				if err != nil {
-					log.WithFields(log.Fields{"error": err, "loadbalancer": lb.ID, "listener": listener.ID}).Fatal("Failed to get listener pools.")
+		if outputFormat == "json" {
+			sort.SliceStable(all, func(i, j int) bool {
+				if all[i].Cloud != all[j].Cloud {
+					return all[i].Cloud < all[j].Cloud
 				}
+				return all[i].Region < all[j].Region
+			})
+			if jsonErr := printJSON(all); jsonErr != nil {
+				return jsonErr
+			}
+		}
+		return err
+	},
+}
 
-				for _, pool := range listenerPools {
-					fmt.Printf("\t\t- Pool: %s, protocol: %s\n", pool.ID, pool.Protocol)
+// loadBalancerTree is a load balancer with its listeners, pools and members. The cloud and region are only set when
+// running across clouds or regions.
+type loadBalancerTree struct {
+	Cloud              string         `json:"cloud,omitempty"`
+	Region             string         `json:"region,omitempty"`
+	ID                 string         `json:"id"`
+	Name               string         `json:"name"`
+	ProvisioningStatus string         `json:"provisioning_status"`
+	VipAddress         string         `json:"vip_address"`
+	FlavorID           string         `json:"flavor_id"`
+	Tags               []string       `json:"tags"`
+	Listeners          []listenerTree `json:"listeners"`
+	// Pools are the shared pools, not the default pool of any listener.
+	Pools []poolTree `json:"pools"`
+}
 
-					// Get pool members
-					members, err := osClient.GetMembers(pool.ID)
-					if err != nil {
-						log.WithFields(log.Fields{"error": err, "loadbalancer": lb.ID, "pool": pool.ID}).Fatal("Failed to get members.")
-					}
+type listenerTree struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Protocol     string     `json:"protocol"`
+	ProtocolPort int        `json:"protocol_port"`
+	Pools        []poolTree `json:"pools"`
+}
 
-					for _, m := range members {
-						fmt.Printf("\t\t\t- Member: %s, address: %s, port: %d\n", m.ID, m.Address, m.ProtocolPort)
-					}
-				}
+type poolTree struct {
+	ID       string       `json:"id"`
+	Protocol string       `json:"protocol"`
+	Members  []memberTree `json:"members"`
+}
+
+type memberTree struct {
+	ID           string `json:"id"`
+	Address      string `json:"address"`
+	ProtocolPort int    `json:"protocol_port"`
+}
+
+// getLoadBalancerTrees gets the load balancers and their sub-resources in the region of the client.
+func getLoadBalancerTrees(ctx context.Context, osClient *myOpenstack.OpenStack) ([]loadBalancerTree, error) {
+	lbs, err := osClient.GetLoadbalancers(ctx, projectID, lbTags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get load balancers: %w", err)
+	}
+
+	trees := make([]loadBalancerTree, 0, len(lbs))
+	for _, lb := range lbs {
+		tree := loadBalancerTree{
+			ID:                 lb.ID,
+			Name:               lb.Name,
+			ProvisioningStatus: lb.ProvisioningStatus,
+			VipAddress:         lb.VipAddress,
+			FlavorID:           lb.FlavorID,
+			Tags:               lb.Tags,
+			Listeners:          []listenerTree{},
+		}
+		if multiCloud() {
+			tree.Cloud = osClient.Cloud()
+		}
+		if multiRegion() {
+			tree.Region = osClient.Region()
+		}
+
+		for _, listener := range lb.Listeners {
+			listenerInfo, err := osClient.GetListener(ctx, listener.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get listener %s of load balancer %s: %w", listener.ID, lb.ID, err)
 			}
 
-			// Get shared pools
-			sharedPools, err := osClient.GetPools(lb.ID, true, "")
+			// Get listener pools, pools can only be retrieved by loadbalancer rather than listener.
+			listenerPools, err := osClient.GetPools(ctx, lb.ID, false, listener.ID)
 			if err != nil {
-				log.WithFields(log.Fields{"error": err, "loadbalancer": lb.ID}).Fatal("Failed to get shared pools.")
+				return nil, fmt.Errorf("failed to get pools of listener %s: %w", listener.ID, err)
+			}
+			pools, err := getPoolTrees(ctx, osClient, listenerPools)
+			if err != nil {
+				return nil, err
 			}
 
-			for _, pool := range sharedPools {
-				fmt.Printf("\t- Pool: %s, protocol: %s\n", pool.ID, pool.Protocol)
+			tree.Listeners = append(tree.Listeners, listenerTree{
+				ID:           listenerInfo.ID,
+				Name:         listenerInfo.Name,
+				Protocol:     listenerInfo.Protocol,
+				ProtocolPort: listenerInfo.ProtocolPort,
+				Pools:        pools,
+			})
+		}
 
-				// Get pool members
-				members, err := osClient.GetMembers(pool.ID)
-				if err != nil {
-					log.WithFields(log.Fields{"error": err, "loadbalancer": lb.ID, "pool": pool.ID}).Fatal("Failed to get members.")
-				}
+		// Get shared pools
+		sharedPools, err := osClient.GetPools(ctx, lb.ID, true, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get shared pools of load balancer %s: %w", lb.ID, err)
+		}
+		if tree.Pools, err = getPoolTrees(ctx, osClient, sharedPools); err != nil {
+			return nil, err
+		}
+
+		trees = append(trees, tree)
+	}
 
-				for _, m := range members {
-					fmt.Printf("\t\t- Member: %s, address: %s, port: %d\n", m.ID, m.Address, m.ProtocolPort)
+	return trees, nil
+}
+
+// getPoolTrees gets the members of the pools.
+func getPoolTrees(ctx context.Context, osClient *myOpenstack.OpenStack, lbPools []pools.Pool) ([]poolTree, error) {
+	trees := make([]poolTree, 0, len(lbPools))
+	for _, pool := range lbPools {
+		members, err := osClient.GetMembers(ctx, pool.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get members of pool %s: %w", pool.ID, err)
+		}
+
+		tree := poolTree{ID: pool.ID, Protocol: pool.Protocol, Members: make([]memberTree, 0, len(members))}
+		for _, m := range members {
+			tree.Members = append(tree.Members, memberTree{ID: m.ID, Address: m.Address, ProtocolPort: m.ProtocolPort})
+		}
+		trees = append(trees, tree)
+	}
+	return trees, nil
+}
+
+// printLoadBalancers prints the load balancers and their sub-resources as text.
+func printLoadBalancers(p *rowPrinter, lbs []loadBalancerTree) {
+	for _, lb := range lbs {
+		var lbInfoList []string
+		lbInfoList = append(lbInfoList, fmt.Sprintf("- LoadBalancer: %s", lb.ID), fmt.Sprintf("status: %s", lb.ProvisioningStatus), fmt.Sprintf("vip: %s", lb.VipAddress))
+		if lb.Name != "" {
+			lbInfoList = append(lbInfoList, fmt.Sprintf("name: %s", lb.Name))
+		}
+		if lb.FlavorID != "" {
+			lbInfoList = append(lbInfoList, fmt.Sprintf("flavor: %s", lb.FlavorID))
+		}
+		if len(lb.Tags) > 0 {
+			lbInfoList = append(lbInfoList, fmt.Sprintf("tags: %s", strings.Join(lb.Tags, ",")))
+		}
+		p.Println(strings.Join(lbInfoList, ", "))
+
+		for _, listener := range lb.Listeners {
+			listenerLine := fmt.Sprintf("\t- Listener: %s, protocol: %s, port: %d", listener.ID, listener.Protocol, listener.ProtocolPort)
+			if listener.Name != "" {
+				listenerLine += fmt.Sprintf(", name: %s", listener.Name)
+			}
+			p.Println(listenerLine)
+
+			for _, pool := range listener.Pools {
+				p.Printf("\t\t- Pool: %s, protocol: %s\n", pool.ID, pool.Protocol)
+				for _, m := range pool.Members {
+					p.Printf("\t\t\t- Member: %s, address: %s, port: %d\n", m.ID, m.Address, m.ProtocolPort)
 				}
 			}
 		}
-	},
+
+		for _, pool := range lb.Pools {
+			p.Printf("\t- Pool: %s, protocol: %s\n", pool.ID, pool.Protocol)
+			for _, m := range pool.Members {
+				p.Printf("\t\t- Member: %s, address: %s, port: %d\n", m.ID, m.Address, m.ProtocolPort)
+			}
+		}
+	}
 }
 
 func init() {
 	getLoadBalancersCmd.Flags().StringVar(&projectID, "project", "", "Only get loadbalancer resources for the given project(admin required).")
+	getLoadBalancersCmd.Flags().StringSliceVar(&lbTags, "tags", nil, "Only get the load balancers with all the given tags, requires octavia API version 2.5.")
+	addRegionFlags(getLoadBalancersCmd)
+	addCloudFlags(getLoadBalancersCmd)
 	getCmd.AddCommand(getLoadBalancersCmd)
 }
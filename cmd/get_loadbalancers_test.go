@@ -0,0 +1,59 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestGetLoadBalancerTrees(t *testing.T) {
+	server, osClient := newFakeCloudClient(t)
+	defer server.Close()
+
+	lbs, err := getLoadBalancerTrees(context.Background(), osClient)
+	if err != nil {
+		t.Fatalf("getLoadBalancerTrees() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	printLoadBalancers(&rowPrinter{w: &buf}, lbs)
+	want := `- LoadBalancer: lb-web, status: ACTIVE, vip: 10.0.0.10, name: web, tags: production
+	- Listener: listener-http, protocol: HTTP, port: 80, name: http
+		- Pool: pool-web, protocol: HTTP
+			- Member: member-web-1, address: 10.0.0.21, port: 8080
+			- Member: member-web-2, address: 10.0.0.22, port: 8080
+- LoadBalancer: lb-db, status: ACTIVE, vip: 10.0.0.11, name: db
+	- Listener: listener-mysql, protocol: TCP, port: 3306
+		- Pool: pool-mysql, protocol: TCP
+			- Member: member-db-1, address: 10.0.0.31, port: 3306
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printLoadBalancers() printed\n%s\nwant\n%s", got, want)
+	}
+
+	data, err := json.Marshal(lbs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{"id":"lb-db","name":"db","provisioning_status":"ACTIVE","vip_address":"10.0.0.11","flavor_id":"","tags":[],"listeners":[` +
+		`{"id":"listener-mysql","name":"","protocol":"TCP","protocol_port":3306,"pools":[` +
+		`{"id":"pool-mysql","protocol":"TCP","members":[{"id":"member-db-1","address":"10.0.0.31","protocol_port":3306}]}]}],"pools":[]}`
+	if string(data) != wantJSON {
+		t.Errorf("getLoadBalancerTrees() in json = %s, want %s", data, wantJSON)
+	}
+}
@@ -15,9 +15,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"sync"
 
-	log "github.com/sirupsen/logrus"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/projects"
 	"github.com/spf13/cobra"
 
 	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
@@ -26,23 +29,58 @@ import (
 var getProjectsCmd = &cobra.Command{
 	Use:   "projects",
 	Short: "Get all projects ID and name(admin only).",
-	Run: func(cmd *cobra.Command, args []string) {
-		osClient, err := myOpenstack.NewOpenStack(conf)
-		if err != nil {
-			log.WithFields(log.Fields{"error": err}).Fatal("Failed to initialize openstack client")
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := commandContext()
+		defer cancel()
 
-		projects, err := osClient.GetProjects()
-		if err != nil {
-			log.WithFields(log.Fields{"error": err}).Fatal("Failed to get projects")
-		}
+		var mu sync.Mutex
+		var all []cloudProject
+
+		err := forEachTarget(ctx, nil, func(ctx context.Context, p *rowPrinter, osClient *myOpenstack.OpenStack) error {
+			projects, err := osClient.GetProjects(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get projects: %w", err)
+			}
+
+			if outputFormat == "json" {
+				var cloud string
+				if multiCloud() {
+					cloud = osClient.Cloud()
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				for _, project := range projects {
+					all = append(all, cloudProject{Cloud: cloud, Project: project})
+				}
+				return nil
+			}
 
-		for _, p := range projects {
-			fmt.Printf("ID: %s, Name: %s\n", p.ID, p.Name)
+			for _, project := range projects {
+				if multiCloud() {
+					fmt.Fprintf(p.w, "Cloud: %s, ", osClient.Cloud())
+				}
+				fmt.Fprintf(p.w, "ID: %s, Name: %s\n", project.ID, project.Name)
+			}
+			return nil
+		})
+
+		if outputFormat == "json" {
+			sort.SliceStable(all, func(i, j int) bool { return all[i].Cloud < all[j].Cloud })
+			if jsonErr := printJSON(all); jsonErr != nil {
+				return jsonErr
+			}
 		}
+		return err
 	},
 }
 
+// cloudProject is a project with the cloud it belongs to.
+type cloudProject struct {
+	Cloud string `json:"cloud,omitempty"`
+	projects.Project
+}
+
 func init() {
+	addCloudFlags(getProjectsCmd)
 	getCmd.AddCommand(getProjectsCmd)
 }
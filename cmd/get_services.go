@@ -0,0 +1,95 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
+)
+
+// catalogService is a service in the service catalog.
+type catalogService struct {
+	Cloud  string `json:"cloud,omitempty"`
+	Region string `json:"region,omitempty"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+}
+
+var getServicesCmd = &cobra.Command{
+	Use:   "services",
+	Short: "Get the services in the service catalog of the token which have endpoints in the region and interface.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := commandContext()
+		defer cancel()
+
+		var mu sync.Mutex
+		var all []catalogService
+
+		err := forEachTarget(ctx, nil, func(ctx context.Context, p *rowPrinter, osClient *myOpenstack.OpenStack) error {
+			entries, err := osClient.Catalog()
+			if err != nil {
+				return fmt.Errorf("failed to get service catalog: %w", err)
+			}
+
+			if outputFormat == "json" {
+				var cloud, region string
+				if multiCloud() {
+					cloud = osClient.Cloud()
+				}
+				if multiRegion() {
+					region = osClient.Region()
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				for _, entry := range entries {
+					all = append(all, catalogService{Cloud: cloud, Region: region, ID: entry.ID, Name: entry.Name, Type: entry.Type})
+				}
+				return nil
+			}
+
+			for _, entry := range entries {
+				p.Printf("ID: %s, Name: %s, Type: %s\n", entry.ID, entry.Name, entry.Type)
+			}
+			return nil
+		})
+
+		if outputFormat == "json" {
+			sort.SliceStable(all, func(i, j int) bool {
+				if all[i].Cloud != all[j].Cloud {
+					return all[i].Cloud < all[j].Cloud
+				}
+				return all[i].Region < all[j].Region
+			})
+			if jsonErr := printJSON(all); jsonErr != nil {
+				return jsonErr
+			}
+		}
+		return err
+	},
+}
+
+func init() {
+	addRegionFlags(getServicesCmd)
+	addCloudFlags(getServicesCmd)
+	getCmd.AddCommand(getServicesCmd)
+}
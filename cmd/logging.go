@@ -0,0 +1,61 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	logLevel  string
+	logFormat string
+	logFile   string
+	quiet     bool
+)
+
+// setupLogging configures the logs from the flags. The logs go to stderr unless --log-file is set, so stdout only
+// carries the command results and can be piped.
+func setupLogging() error {
+	level, err := log.ParseLevel(logLevel)
+	if err != nil {
+		return &usageError{fmt.Errorf("invalid log level %q, expected one of debug, info, warning, error", logLevel)}
+	}
+	if quiet {
+		level = log.ErrorLevel
+	}
+
+	switch logFormat {
+	case "text":
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return &usageError{fmt.Errorf("invalid log format %q, text or json expected", logFormat)}
+	}
+
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		log.SetOutput(f)
+	}
+
+	log.SetLevel(level)
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
+)
+
+var (
+	metricsFile     string
+	metricsInterval time.Duration
+	// metricsMu serializes the writes of the metrics file.
+	metricsMu sync.Mutex
+)
+
+// startMetrics enables the API call metrics if --metrics-file is set. The file is written periodically and when osctl
+// exits.
+func startMetrics() error {
+	if metricsFile == "" {
+		return nil
+	}
+	if metricsInterval <= 0 {
+		return &usageError{fmt.Errorf("invalid metrics interval %s", metricsInterval)}
+	}
+
+	conf.Metrics = true
+
+	go func() {
+		for range time.Tick(metricsInterval) {
+			writeMetrics()
+		}
+	}()
+
+	return nil
+}
+
+// writeMetrics writes the metrics file, a failure is only logged.
+func writeMetrics() {
+	if !conf.Metrics {
+		return
+	}
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if err := myOpenstack.WriteMetricsFile(metricsFile); err != nil {
+		log.WithFields(log.Fields{"error": err, "file": metricsFile}).Warn("Failed to write metrics file")
+	}
+}
@@ -17,83 +17,246 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	homedir "github.com/mitchellh/go-homedir"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
+	"github.com/lingxiankong/openstackcli-go/pkg/config"
 	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
 )
 
 var (
-	cfgFile string
-	conf    myOpenstack.OpenStackConfig
+	cfgFile      string
+	contextName  string
+	outputFormat string
+	endpoints    []string
+	openRCFile   string
+	conf         myOpenstack.OpenStackConfig
+	// baseConf is the config the clouds of --clouds and --all-clouds start from, see cloudBaseConfig.
+	baseConf myOpenstack.OpenStackConfig
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "osctl",
 	Short: "A simple command line tool written in Go",
+	Long:  "A simple command line tool written in Go.\n\n" + exitCodesHelp,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := startCommand(cmd); err != nil {
+			return err
+		}
+		if err := initConfig(cmd); err != nil {
+			return err
+		}
+		return startTracing(cmd, args)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// osctl exits with the code of the error class, see exitCodesHelp.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	err := rootCmd.Execute()
+	rootSpan.RecordError(err)
+	stopTracing()
+	writeMetrics()
+	if err != nil {
+		code := exitCode(err)
+		// The errors of the command line are printed by cobra with the usage.
+		if commandStarted {
+			log.WithFields(log.Fields{"error": err, "exit_code": code}).Error("Command failed")
+		}
+		os.Exit(code)
 	}
 }
 
 func init() {
-	log.SetOutput(os.Stdout)
+	log.SetOutput(os.Stderr)
 	log.SetLevel(log.InfoLevel)
 	log.SetFormatter(&log.TextFormatter{
 		FullTimestamp: true,
 	})
+	log.AddHook(myOpenstack.RequestIDHook{})
 
-	cobra.OnInitialize(initConfig)
-
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.runit.yaml)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", envString("OSCTL_LOG_LEVEL", "info"), "log level, debug, info, warning or error")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", envString("OSCTL_LOG_FORMAT", "text"), "log format, text or json")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", os.Getenv("OSCTL_LOG_FILE"), "append the logs to the file instead of writing them to stderr")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", os.Getenv("OSCTL_QUIET") == "true", "only log errors, same as --log-level error")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "osctl config file (default is $OSCTL_CONFIG or $HOME/.config/osctl/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "context in the osctl config file to use instead of the current context")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "output format of the get and auth commands, text or json (default \"text\")")
+	rootCmd.PersistentFlags().StringVar(&conf.Cloud, "os-cloud", os.Getenv("OS_CLOUD"), "cloud name in clouds.yaml")
+	rootCmd.PersistentFlags().StringVar(&openRCFile, "openrc", "", "openrc file to read the OS_* variables from, the file is parsed rather than run")
 	rootCmd.PersistentFlags().StringVarP(&conf.Username, "user-name", "u", os.Getenv("OS_USERNAME"), "user name")
 	rootCmd.PersistentFlags().StringVarP(&conf.Password, "password", "p", os.Getenv("OS_PASSWORD"), "user password")
 	rootCmd.PersistentFlags().StringVarP(&conf.ProjectName, "project-name", "", os.Getenv("OS_PROJECT_NAME"), "project name")
+	rootCmd.PersistentFlags().StringVar(&conf.ProjectID, "os-project-id", os.Getenv("OS_PROJECT_ID"), "project ID, takes precedence over project name")
+	rootCmd.PersistentFlags().StringVar(&conf.UserDomainName, "os-user-domain-name", os.Getenv("OS_USER_DOMAIN_NAME"), "user domain name (default \"default\")")
+	rootCmd.PersistentFlags().StringVar(&conf.UserDomainID, "os-user-domain-id", os.Getenv("OS_USER_DOMAIN_ID"), "user domain ID")
+	rootCmd.PersistentFlags().StringVar(&conf.ProjectDomainName, "os-project-domain-name", os.Getenv("OS_PROJECT_DOMAIN_NAME"), "project domain name (default is the user domain)")
+	rootCmd.PersistentFlags().StringVar(&conf.ProjectDomainID, "os-project-domain-id", os.Getenv("OS_PROJECT_DOMAIN_ID"), "project domain ID")
+	rootCmd.PersistentFlags().StringVar(&conf.DomainName, "os-domain-name", os.Getenv("OS_DOMAIN_NAME"), "domain name for a domain scoped token")
+	rootCmd.PersistentFlags().StringVar(&conf.DomainID, "os-domain-id", os.Getenv("OS_DOMAIN_ID"), "domain ID for a domain scoped token")
+	rootCmd.PersistentFlags().StringVar(&conf.SystemScope, "os-system-scope", os.Getenv("OS_SYSTEM_SCOPE"), "request a system scoped token, e.g. \"all\"")
 	rootCmd.PersistentFlags().StringVarP(&conf.Region, "region", "r", os.Getenv("OS_REGION_NAME"), "region name")
 	rootCmd.PersistentFlags().StringVarP(&conf.AuthURL, "authurl", "a", os.Getenv("OS_AUTH_URL"), "auth url")
+	rootCmd.PersistentFlags().StringVar(&conf.ApplicationCredentialID, "os-application-credential-id", os.Getenv("OS_APPLICATION_CREDENTIAL_ID"), "application credential ID")
+	rootCmd.PersistentFlags().StringVar(&conf.ApplicationCredentialName, "os-application-credential-name", os.Getenv("OS_APPLICATION_CREDENTIAL_NAME"), "application credential name, requires --user-name")
+	rootCmd.PersistentFlags().StringVar(&conf.ApplicationCredentialSecret, "os-application-credential-secret", os.Getenv("OS_APPLICATION_CREDENTIAL_SECRET"), "application credential secret")
+	rootCmd.PersistentFlags().StringVar(&conf.Interface, "os-interface", os.Getenv("OS_INTERFACE"), "endpoint interface, public, internal or admin (default \"public\")")
+	rootCmd.PersistentFlags().StringSliceVar(&endpoints, "os-endpoint-override", nil, "SERVICE=URL, use the URL instead of the catalog endpoint of the service(octavia, nova, neutron, glance or keystone)")
+	rootCmd.PersistentFlags().StringVar(&conf.ComputeAPIVersion, "os-compute-api-version", os.Getenv("OS_COMPUTE_API_VERSION"), "nova API microversion, e.g. 2.79 (default is the highest version supported by both osctl and nova)")
+	rootCmd.PersistentFlags().StringVar(&conf.LoadBalancerAPIVersion, "os-load-balancer-api-version", os.Getenv("OS_LOAD_BALANCER_API_VERSION"), "octavia API version, e.g. 2.13 (default is the highest version supported by both osctl and octavia)")
+	rootCmd.PersistentFlags().StringVar(&conf.CACert, "os-cacert", os.Getenv("OS_CACERT"), "CA bundle file to verify the TLS certificates of the API endpoints")
+	rootCmd.PersistentFlags().StringVar(&conf.ClientCert, "os-cert", os.Getenv("OS_CERT"), "client certificate file for mutual TLS")
+	rootCmd.PersistentFlags().StringVar(&conf.ClientKey, "os-key", os.Getenv("OS_KEY"), "client certificate key file for mutual TLS")
+	rootCmd.PersistentFlags().BoolVar(&conf.Insecure, "insecure", os.Getenv("OS_INSECURE") == "true", "skip TLS certificate verification (not secure)")
+	rootCmd.PersistentFlags().IntVar(&conf.RetryMaxAttempts, "retry-max-attempts", envInt("OSCTL_RETRY_MAX_ATTEMPTS", myOpenstack.DefaultRetryMaxAttempts), "number of attempts of a request failed with a transient error(409, 429, 502, 503, 504 or connection errors), 1 disables retries")
+	rootCmd.PersistentFlags().BoolVar(&conf.RetryAllMethods, "retry-all-methods", os.Getenv("OSCTL_RETRY_ALL_METHODS") == "true", "retry POST requests as well, and PUT and DELETE requests on all the transient errors rather than only on 409, 429 and 503")
+	rootCmd.PersistentFlags().Float64Var(&conf.MaxQPS, "max-qps", envFloat("OSCTL_MAX_QPS", 0), "maximum requests per second to all the OpenStack services, 0 means unlimited")
+	rootCmd.PersistentFlags().BoolVar(&conf.DebugHTTP, "debug-http", false, "log every HTTP request and response, the tokens, passwords and secrets are redacted")
+	rootCmd.PersistentFlags().BoolVar(&conf.DebugHTTPBodies, "debug-http-bodies", false, "log the headers and bodies as well, implies --debug-http")
+	rootCmd.PersistentFlags().StringVar(&conf.DebugHTTPFile, "debug-http-file", "", "write the HTTP trace to the file instead of the log, implies --debug-http")
+	rootCmd.PersistentFlags().StringVar(&conf.Record, "record", "", "save every HTTP interaction as a cassette file in the directory, the tokens and credentials are redacted")
+	rootCmd.PersistentFlags().StringVar(&conf.Replay, "replay", "", "serve the HTTP interactions recorded in the directory instead of calling the cloud")
+	rootCmd.PersistentFlags().StringVar(&conf.ReplayMode, "replay-mode", myOpenstack.ReplayStrict, "strict: same URLs and bodies, each interaction replayed once; lenient: same paths and query parameters, interactions can be replayed again")
+	rootCmd.PersistentFlags().StringVar(&metricsFile, "metrics-file", os.Getenv("OSCTL_METRICS_FILE"), "write the API call metrics to the file in Prometheus text format at exit and periodically, e.g. for the textfile collector of node_exporter")
+	rootCmd.PersistentFlags().DurationVar(&metricsInterval, "metrics-interval", 15*time.Second, "how often the metrics file is written during the run")
+	rootCmd.PersistentFlags().StringVar(&traceFile, "trace-file", os.Getenv("OSCTL_TRACE_FILE"), "append the trace spans of the run to the file as OTLP-JSON lines")
+	rootCmd.PersistentFlags().StringVar(&traceEndpoint, "trace-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "send the trace spans of the run to the OTLP/HTTP endpoint, e.g. http://localhost:4318")
+	rootCmd.PersistentFlags().BoolVar(&conf.TokenCache, "token-cache", os.Getenv("OSCTL_TOKEN_CACHE") == "true", "cache the keystone token on disk and reuse it across invocations")
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := homedir.Dir()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+// envString returns the value of the environment variable, or def if it's not set.
+func envString(name string, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// envInt returns the integer value of the environment variable, or def if it's not set or not an integer.
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil {
+		return v
+	}
+	return def
+}
+
+// envFloat returns the float value of the environment variable, or def if it's not set or not a number.
+func envFloat(name string, def float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(name), 64); err == nil {
+		return v
+	}
+	return def
+}
+
+// loadConfigFile reads the osctl config file.
+func loadConfigFile() (*config.Config, string, error) {
+	path := cfgFile
+	if path == "" {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			return nil, "", fmt.Errorf("failed to find config file: %w", err)
+		}
+	}
+
+	c, err := config.Load(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	return c, path, nil
+}
+
+// applyContextSettings applies the settings of the context other than the cloud and region to the config, the flags
+// and environment variables take precedence.
+func applyContextSettings(cfg *myOpenstack.OpenStackConfig, ctx *config.Context) {
+	if cfg.Interface == "" {
+		cfg.Interface = ctx.Interface
+	}
+	cfg.SetEndpointOverrides(ctx.Endpoints)
+	if cfg.PasswordCommand == "" {
+		cfg.PasswordCommand = ctx.PasswordCommand
+	}
+	if cfg.MaxQPS == 0 {
+		cfg.MaxQPS = ctx.MaxQPS
+	}
+	cfg.ServiceQPS = ctx.RateLimits
+}
+
+// initConfig reads in the active context of the config file and the clouds.yaml entry it refers to. Flags and
+// environment variables take precedence over both.
+func initConfig(cmd *cobra.Command) error {
+	c, path, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range endpoints {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return &usageError{fmt.Errorf("invalid endpoint override %q, SERVICE=URL expected", e)}
 		}
+		conf.SetEndpointOverrides(map[string]string{parts[0]: parts[1]})
+	}
 
-		// Search config in home directory with name ".runit" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigName(".runit")
+	if err := startMetrics(); err != nil {
+		return err
 	}
+	baseConf = cloudBaseConfig(cmd)
 
-	viper.AutomaticEnv() // read in environment variables that match
+	if openRCFile != "" {
+		vars, err := myOpenstack.ParseOpenRC(openRCFile)
+		if err != nil {
+			return fmt.Errorf("failed to read openrc file %s: %w", openRCFile, err)
+		}
+		conf.MergeEnv(vars)
+	}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		log.WithFields(log.Fields{"error": err}).Fatal("Failed to read config file")
+	name := contextName
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name != "" {
+		ctx := c.Context(name)
+		if ctx == nil {
+			return fmt.Errorf("context %s not found in %s", name, path)
+		}
+		log.WithFields(log.Fields{"context": name, "file": path}).Debug("Using context")
+
+		if conf.Cloud == "" {
+			conf.Cloud = ctx.Cloud
+		}
+		if conf.Region == "" {
+			conf.Region = ctx.Region
+		}
+		if projectID == "" {
+			projectID = ctx.Project
+		}
+		if outputFormat == "" {
+			outputFormat = ctx.Output
+		}
+		applyContextSettings(&conf, ctx)
+		applyContextSettings(&baseConf, ctx)
 	}
 
-	log.WithFields(log.Fields{"file": viper.ConfigFileUsed()}).Debug("Using config file")
+	if outputFormat == "" {
+		outputFormat = "text"
+	}
+	if outputFormat != "text" && outputFormat != "json" {
+		return &usageError{fmt.Errorf("invalid output format %q, text or json expected", outputFormat)}
+	}
 
-	if err := viper.Unmarshal(&conf); err != nil {
-		log.WithFields(log.Fields{"error": err}).Fatal("Unable to parse the configuration")
+	if err := conf.MergeCloud(); err != nil {
+		return fmt.Errorf("failed to load cloud %s from clouds.yaml: %w", conf.Cloud, err)
 	}
+
+	return nil
 }
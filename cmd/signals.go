@@ -0,0 +1,79 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// forcedExitFlushTimeout bounds the export of the trace spans on a forced exit, e.g. to an unreachable endpoint.
+const forcedExitFlushTimeout = time.Second
+
+// commandContext returns the context of a command run. The first SIGINT or SIGTERM cancels it so the running requests
+// and waits stop right away, the second one exits without waiting for them. The returned cancel func also stops the
+// signal handling.
+func commandContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(rootContext)
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	stopped := make(chan struct{})
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			signal.Stop(sigs)
+			close(stopped)
+			cancel()
+		})
+	}
+
+	go func() {
+		select {
+		case <-sigs:
+			log.Warn("Interrupted, cancelling the running operations. Press Ctrl-C again to force exit")
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-sigs:
+		case <-stopped:
+			return
+		}
+		log.Warn("Forced exit")
+		rootSpan.RecordError(ctx.Err())
+		flushed := make(chan struct{})
+		go func() {
+			stopTracing()
+			close(flushed)
+		}()
+		select {
+		case <-flushed:
+		case <-time.After(forcedExitFlushTimeout):
+			log.Warn("Gave up exporting the trace spans")
+		}
+		writeMetrics()
+		os.Exit(exitInterrupted)
+	}()
+
+	return ctx, stop
+}
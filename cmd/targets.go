@@ -0,0 +1,289 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
+	"github.com/lingxiankong/openstackcli-go/pkg/tracing"
+)
+
+var (
+	regions    []string
+	allRegions bool
+	clouds     []string
+	allClouds  bool
+)
+
+// addRegionFlags adds the flags to run the command across several regions.
+func addRegionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&regions, "regions", nil, "Run the command in the given regions, the token is shared by all the regions.")
+	cmd.Flags().BoolVar(&allRegions, "all-regions", false, "Run the command in all the regions of the service catalog with the services it uses.")
+}
+
+// addCloudFlags adds the flags to run the command against several clouds.
+func addCloudFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&clouds, "clouds", nil, "Run the command against the given clouds in clouds.yaml concurrently.")
+	cmd.Flags().BoolVar(&allClouds, "all-clouds", false, "Run the command against all the clouds in clouds.yaml concurrently.")
+}
+
+// multiRegion returns true if the command runs across several regions.
+func multiRegion() bool {
+	return allRegions || len(regions) > 0
+}
+
+// multiCloud returns true if the command runs against several clouds.
+func multiCloud() bool {
+	return allClouds || len(clouds) > 0
+}
+
+// regionClients returns a client per region selected by --regions or --all-regions, or the given client if neither
+// is specified. --all-regions selects the regions with an endpoint of every service the command uses.
+func regionClients(osClient *myOpenstack.OpenStack, services []string) ([]*myOpenstack.OpenStack, error) {
+	if !multiRegion() {
+		return []*myOpenstack.OpenStack{osClient}, nil
+	}
+
+	names := regions
+	if allRegions {
+		var err error
+		if names, err = osClient.Regions(services...); err != nil {
+			return nil, err
+		}
+		if len(names) == 0 && len(services) > 0 {
+			return nil, fmt.Errorf("no region with %s found in the service catalog", strings.Join(services, ", "))
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no region found in the service catalog")
+		}
+	}
+
+	var clients []*myOpenstack.OpenStack
+	for _, name := range names {
+		clients = append(clients, osClient.ForRegion(name))
+	}
+
+	return clients, nil
+}
+
+// cloudBaseConfig returns the current config without the cloud settings that don't come from explicitly given flags.
+// The OS_* variables the flags default to describe the current cloud only, they would override the clouds.yaml entry
+// of every cloud of --clouds and --all-clouds since MergeCloud only fills the empty fields.
+func cloudBaseConfig(cmd *cobra.Command) myOpenstack.OpenStackConfig {
+	cfg := conf.Clone()
+	fields := map[string]*string{
+		"os-cloud":                         &cfg.Cloud,
+		"user-name":                        &cfg.Username,
+		"password":                         &cfg.Password,
+		"project-name":                     &cfg.ProjectName,
+		"os-project-id":                    &cfg.ProjectID,
+		"os-user-domain-name":              &cfg.UserDomainName,
+		"os-user-domain-id":                &cfg.UserDomainID,
+		"os-project-domain-name":           &cfg.ProjectDomainName,
+		"os-project-domain-id":             &cfg.ProjectDomainID,
+		"os-domain-name":                   &cfg.DomainName,
+		"os-domain-id":                     &cfg.DomainID,
+		"os-system-scope":                  &cfg.SystemScope,
+		"region":                           &cfg.Region,
+		"authurl":                          &cfg.AuthURL,
+		"os-application-credential-id":     &cfg.ApplicationCredentialID,
+		"os-application-credential-name":   &cfg.ApplicationCredentialName,
+		"os-application-credential-secret": &cfg.ApplicationCredentialSecret,
+		"os-interface":                     &cfg.Interface,
+		"os-compute-api-version":           &cfg.ComputeAPIVersion,
+		"os-load-balancer-api-version":     &cfg.LoadBalancerAPIVersion,
+		"os-cacert":                        &cfg.CACert,
+		"os-cert":                          &cfg.ClientCert,
+		"os-key":                           &cfg.ClientKey,
+	}
+	flags := cmd.Flags()
+	for name, field := range fields {
+		if !flags.Changed(name) {
+			*field = ""
+		}
+	}
+	if !flags.Changed("insecure") {
+		cfg.Insecure = false
+	}
+	return cfg
+}
+
+// cloudConfigs returns the config of each cloud selected by --clouds or --all-clouds, or the current config if
+// neither is specified.
+func cloudConfigs() ([]myOpenstack.OpenStackConfig, error) {
+	if !multiCloud() {
+		return []myOpenstack.OpenStackConfig{conf}, nil
+	}
+
+	names := clouds
+	if allClouds {
+		all, err := myOpenstack.LoadClouds()
+		if err != nil {
+			return nil, err
+		}
+		names = nil
+		for name := range all {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var cfgs []myOpenstack.OpenStackConfig
+	for _, name := range names {
+		cfg := baseConf.Clone()
+		cfg.Cloud = name
+		cfgs = append(cfgs, cfg)
+	}
+
+	return cfgs, nil
+}
+
+// targetResult is the output of the command in a cloud and region.
+type targetResult struct {
+	cloud  string
+	region string
+	output bytes.Buffer
+	err    error
+}
+
+// forEachTarget runs fn in every cloud and region selected by the flags concurrently, then prints the output of each
+// target in order. services are the services fn uses, see regionClients. A failure in a cloud or region is logged and doesn't stop the others. The errors of the failed
+// targets are combined by myOpenstack.CombineErrors, so a failure in some targets only is a partial failure.
+func forEachTarget(ctx context.Context, services []string, fn func(ctx context.Context, p *rowPrinter, osClient *myOpenstack.OpenStack) error) error {
+	cfgs, err := cloudConfigs()
+	if err != nil {
+		return fmt.Errorf("failed to load clouds: %w", err)
+	}
+
+	results := make([][]*targetResult, len(cfgs))
+	var wg sync.WaitGroup
+	for i, cfg := range cfgs {
+		wg.Add(1)
+		go func(i int, cfg myOpenstack.OpenStackConfig) {
+			defer wg.Done()
+			results[i] = runInCloud(ctx, cfg, services, fn)
+		}(i, cfg)
+	}
+	wg.Wait()
+
+	var total int
+	var errs []error
+	for _, cloudResults := range results {
+		for _, r := range cloudResults {
+			total++
+			os.Stdout.Write(r.output.Bytes())
+			if r.err == nil {
+				continue
+			}
+			// A single target fails the command, Execute logs the error.
+			if !multiCloud() && !multiRegion() {
+				errs = append(errs, r.err)
+				continue
+			}
+
+			fields := log.Fields{"error": r.err}
+			var target []string
+			if r.cloud != "" {
+				fields["cloud"] = r.cloud
+				target = append(target, r.cloud)
+			}
+			if r.region != "" {
+				fields["region"] = r.region
+				target = append(target, r.region)
+			}
+			log.WithFields(fields).Error("Target failed")
+			errs = append(errs, fmt.Errorf("%s: %w", strings.Join(target, "/"), r.err))
+		}
+	}
+
+	return myOpenstack.CombineErrors(total, errs)
+}
+
+// runInCloud runs fn in all the selected regions of the cloud concurrently.
+func runInCloud(ctx context.Context, cfg myOpenstack.OpenStackConfig, services []string, fn func(ctx context.Context, p *rowPrinter, osClient *myOpenstack.OpenStack) error) []*targetResult {
+	fail := func(err error) []*targetResult {
+		return []*targetResult{{cloud: cfg.Cloud, region: cfg.Region, err: err}}
+	}
+
+	if err := cfg.MergeCloud(); err != nil {
+		return fail(err)
+	}
+	osClient, err := myOpenstack.NewOpenStack(ctx, cfg)
+	if err != nil {
+		return fail(fmt.Errorf("failed to initialize openstack client: %w", err))
+	}
+	clients, err := regionClients(osClient, services)
+	if err != nil {
+		return fail(fmt.Errorf("failed to get regions: %w", err))
+	}
+
+	results := make([]*targetResult, len(clients))
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		results[i] = &targetResult{cloud: cfg.Cloud, region: c.Region()}
+		wg.Add(1)
+		go func(r *targetResult, c *myOpenstack.OpenStack) {
+			defer wg.Done()
+			ctx, span := tracing.Start(ctx, "target", tracing.String("cloud", r.cloud), tracing.String("region", r.region))
+			defer span.End()
+			r.err = fn(ctx, newRowPrinter(&r.output, c), c)
+			span.RecordError(r.err)
+		}(results[i], c)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// rowPrinter prints output rows with a prefix, e.g. the cloud and region the row belongs to.
+type rowPrinter struct {
+	w      io.Writer
+	prefix string
+}
+
+// newRowPrinter returns a printer that tags the rows with the cloud and region of the client when running across
+// clouds or regions.
+func newRowPrinter(w io.Writer, osClient *myOpenstack.OpenStack) *rowPrinter {
+	p := &rowPrinter{w: w}
+	switch {
+	case multiCloud() && multiRegion():
+		p.prefix = fmt.Sprintf("[%s/%s] ", osClient.Cloud(), osClient.Region())
+	case multiCloud():
+		p.prefix = fmt.Sprintf("[%s] ", osClient.Cloud())
+	case multiRegion():
+		p.prefix = fmt.Sprintf("[%s] ", osClient.Region())
+	}
+	return p
+}
+
+func (p *rowPrinter) Printf(format string, a ...interface{}) {
+	fmt.Fprintf(p.w, p.prefix+format, a...)
+}
+
+func (p *rowPrinter) Println(a ...interface{}) {
+	fmt.Fprint(p.w, p.prefix)
+	fmt.Fprintln(p.w, a...)
+}
@@ -0,0 +1,71 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/lingxiankong/openstackcli-go/pkg/tracing"
+)
+
+var (
+	traceFile     string
+	traceEndpoint string
+	// rootContext carries the root span of the command, the contexts of the command run derive from it.
+	rootContext = context.Background()
+	rootSpan    *tracing.Span
+)
+
+// startTracing enables tracing if --trace-file or --trace-endpoint is set and starts the root span of the command.
+func startTracing(cmd *cobra.Command, args []string) error {
+	if traceFile == "" && traceEndpoint == "" {
+		return nil
+	}
+	if traceFile != "" && traceEndpoint != "" {
+		return &usageError{errors.New("--trace-file and --trace-endpoint can't be used together")}
+	}
+
+	var exporter tracing.Exporter
+	var err error
+	if traceFile != "" {
+		exporter, err = tracing.NewFileExporter(traceFile)
+	} else {
+		exporter, err = tracing.NewHTTPExporter(traceEndpoint)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
+	tracing.Init(exporter, 10*time.Second)
+
+	rootContext, rootSpan = tracing.Start(context.Background(), cmd.CommandPath(), tracing.String("osctl.args", strings.Join(args, " ")))
+	log.WithFields(log.Fields{"trace_id": rootSpan.TraceID()}).Debug("Tracing enabled")
+	return nil
+}
+
+// stopTracing ends the root span and exports the remaining spans.
+func stopTracing() {
+	rootSpan.End()
+	if err := tracing.Shutdown(); err != nil {
+		log.WithFields(log.Fields{"error": err}).Warn("Failed to export trace spans")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	myOpenstack "github.com/lingxiankong/openstackcli-go/pkg/openstack"
+)
+
+var (
+	waitFor             string
+	waitOperatingStatus string
+	waitFailOn          []string
+	waitTimeout         time.Duration
+	waitInterval        time.Duration
+	waitMaxInterval     time.Duration
+	waitBackoff         float64
+)
+
+var waitCmd = &cobra.Command{
+	Use:   "wait <type> <id>",
+	Short: "Wait for a resource to reach a status, e.g. osctl wait loadbalancer <id> --for provisioning_status=ACTIVE",
+	Long: fmt.Sprintf(`Wait for a resource to reach a status.
+
+Supported types: %s.
+
+The load balancers, listeners and pools wait for provisioning_status=ACTIVE by default, the amphorae for
+status=ALLOCATED and the servers for status=ACTIVE. The wait fails as soon as the default field goes to ERROR unless
+--fail-on is given. Waiting for DELETED succeeds once the resource is gone.`, strings.Join(myOpenstack.WaitResourceTypes(), ", ")),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return fmt.Errorf("expected <type> <id>, got %d args", len(args))
+		}
+		if waitFor != "" {
+			if parts := strings.SplitN(waitFor, "=", 2); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return fmt.Errorf("invalid --for %q, expected <field>=<status>", waitFor)
+			}
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := commandContext()
+		defer cancel()
+
+		opts := myOpenstack.WaitOpts{
+			OperatingStatus: strings.ToUpper(waitOperatingStatus),
+			Timeout:         waitTimeout,
+			Interval:        waitInterval,
+			MaxInterval:     waitMaxInterval,
+			Backoff:         waitBackoff,
+		}
+		if waitFor != "" {
+			parts := strings.SplitN(waitFor, "=", 2)
+			opts.Field, opts.Status = parts[0], strings.ToUpper(parts[1])
+		}
+		if cmd.Flags().Changed("fail-on") {
+			opts.FailStates = []string{}
+			for _, s := range waitFailOn {
+				opts.FailStates = append(opts.FailStates, strings.ToUpper(s))
+			}
+		}
+
+		if err := opts.Validate(args[0]); err != nil {
+			return &usageError{err}
+		}
+
+		osClient, err := myOpenstack.NewOpenStack(ctx, conf)
+		if err != nil {
+			return fmt.Errorf("failed to initialize openstack client: %w", err)
+		}
+
+		if err := osClient.Wait(ctx, args[0], args[1], opts); err != nil {
+			return fmt.Errorf("failed to wait for %s %s: %w", args[0], args[1], err)
+		}
+		log.WithFields(log.Fields{"type": args[0], "id": args[1]}).Info("Condition met")
+		return nil
+	},
+}
+
+func init() {
+	waitCmd.Flags().StringVar(&waitFor, "for", "", "The status to wait for as <field>=<status>, e.g. provisioning_status=ACTIVE.")
+	waitCmd.Flags().StringVar(&waitOperatingStatus, "operating-status", "", "The operating status the load balancer, listener or pool must also reach, e.g. ONLINE.")
+	waitCmd.Flags().StringSliceVar(&waitFailOn, "fail-on", nil, "The statuses of the --for field which fail the wait, by default the wait fails when the default field goes to ERROR.")
+	waitCmd.Flags().DurationVar(&waitTimeout, "timeout", 10*time.Minute, "Maximum duration of the wait, 0 to wait until interrupted.")
+	waitCmd.Flags().DurationVar(&waitInterval, "interval", myOpenstack.DefaultWaitInterval, "Delay between the first polls.")
+	waitCmd.Flags().DurationVar(&waitMaxInterval, "max-interval", myOpenstack.DefaultWaitMaxInterval, "Maximum delay between the polls.")
+	waitCmd.Flags().Float64Var(&waitBackoff, "backoff", myOpenstack.DefaultWaitBackoff, "Factor the delay between the polls grows by after each poll.")
+
+	rootCmd.AddCommand(waitCmd)
+}
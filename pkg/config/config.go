@@ -0,0 +1,209 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the content of the osctl config file.
+type Config struct {
+	CurrentContext string    `yaml:"current-context"`
+	Contexts       []Context `yaml:"contexts"`
+}
+
+// Context is a named set of settings for an OpenStack cloud.
+type Context struct {
+	Name string `yaml:"name"`
+	// Cloud refers to the credentials entry in clouds.yaml.
+	Cloud  string `yaml:"cloud,omitempty"`
+	Region string `yaml:"region,omitempty"`
+	// Interface is the endpoint interface, public, internal or admin.
+	Interface string `yaml:"interface,omitempty"`
+	// Endpoints overrides the catalog endpoints of the services, e.g. octavia.
+	Endpoints map[string]string `yaml:"endpoints,omitempty"`
+	// Project is the default project ID used to filter resources.
+	Project string `yaml:"project,omitempty"`
+	Output  string `yaml:"output,omitempty"`
+	// PasswordCommand is run by the shell to get the password, its stdout is used as the password.
+	PasswordCommand string `yaml:"password_command,omitempty"`
+	// MaxQPS limits the requests per second to all the services, RateLimits limits the requests per second to each
+	// service, e.g. octavia.
+	MaxQPS     float64            `yaml:"max_qps,omitempty"`
+	RateLimits map[string]float64 `yaml:"rate_limits,omitempty"`
+}
+
+// DefaultPath returns the path of the config file, $OSCTL_CONFIG or $XDG_CONFIG_HOME/osctl/config.yaml.
+func DefaultPath() (string, error) {
+	if f := os.Getenv("OSCTL_CONFIG"); f != "" {
+		return f, nil
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", err
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(configHome, "osctl", "config.yaml"), nil
+}
+
+// Load reads the config file, an empty config is returned if the file doesn't exist.
+func Load(path string) (*Config, error) {
+	c := &Config{}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	return c, nil
+}
+
+// Save writes the config file, the file is only accessible by the current user.
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Context returns the context with the given name, or nil if not found.
+func (c *Config) Context(name string) *Context {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			return &c.Contexts[i]
+		}
+	}
+	return nil
+}
+
+// SetContext adds the context or replaces the existing one with the same name.
+func (c *Config) SetContext(ctx Context) {
+	if existing := c.Context(ctx.Name); existing != nil {
+		*existing = ctx
+		return
+	}
+	c.Contexts = append(c.Contexts, ctx)
+}
+
+// DeleteContext removes the context, the current context is unset if it's deleted.
+func (c *Config) DeleteContext(name string) error {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			c.Contexts = append(c.Contexts[:i], c.Contexts[i+1:]...)
+			if c.CurrentContext == name {
+				c.CurrentContext = ""
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("context %s not found", name)
+}
+
+// Set sets a context field by its key in the config file.
+func (ctx *Context) Set(key, value string) error {
+	switch key {
+	case "cloud":
+		ctx.Cloud = value
+	case "region":
+		ctx.Region = value
+	case "project":
+		ctx.Project = value
+	case "output":
+		if value != "" && value != "text" && value != "json" {
+			return fmt.Errorf("invalid output format %q, text or json expected", value)
+		}
+		ctx.Output = value
+	case "interface":
+		if value != "" && value != "public" && value != "internal" && value != "admin" {
+			return fmt.Errorf("invalid endpoint interface %q, public, internal or admin expected", value)
+		}
+		ctx.Interface = value
+	case "password_command":
+		ctx.PasswordCommand = value
+	case "max_qps":
+		qps, err := parseQPS(value)
+		if err != nil {
+			return err
+		}
+		ctx.MaxQPS = qps
+	default:
+		if service := strings.TrimPrefix(key, "rate_limits."); service != key && service != "" {
+			if value == "" {
+				delete(ctx.RateLimits, service)
+				return nil
+			}
+			qps, err := parseQPS(value)
+			if err != nil {
+				return err
+			}
+			if ctx.RateLimits == nil {
+				ctx.RateLimits = make(map[string]float64)
+			}
+			ctx.RateLimits[service] = qps
+			return nil
+		}
+		if service := strings.TrimPrefix(key, "endpoints."); service != key && service != "" {
+			if value == "" {
+				delete(ctx.Endpoints, service)
+				return nil
+			}
+			if ctx.Endpoints == nil {
+				ctx.Endpoints = make(map[string]string)
+			}
+			ctx.Endpoints[service] = value
+			return nil
+		}
+		return fmt.Errorf("unknown context key %s", key)
+	}
+	return nil
+}
+
+// parseQPS parses a rate limit in requests per second, "" means unlimited.
+func parseQPS(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	qps, err := strconv.ParseFloat(value, 64)
+	if err != nil || qps < 0 {
+		return 0, fmt.Errorf("invalid requests per second %q", value)
+	}
+	return qps, nil
+}
@@ -0,0 +1,59 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContextSet(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		want    Context
+		wantErr bool
+	}{
+		{key: "output", value: "json", want: Context{Output: "json"}},
+		{key: "output", value: "", want: Context{}},
+		{key: "output", value: "yaml", wantErr: true},
+		{key: "interface", value: "internal", want: Context{Interface: "internal"}},
+		{key: "interface", value: "private", wantErr: true},
+		{key: "max_qps", value: "2.5", want: Context{MaxQPS: 2.5}},
+		{key: "max_qps", value: "-1", wantErr: true},
+		{key: "rate_limits.octavia", value: "5", want: Context{RateLimits: map[string]float64{"octavia": 5}}},
+		{key: "endpoints.nova", value: "http://nova:8774/v2.1", want: Context{Endpoints: map[string]string{"nova": "http://nova:8774/v2.1"}}},
+		{key: "flavor", value: "m1.small", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			var ctx Context
+			err := ctx.Set(tt.key, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Set() = %+v, want an error", ctx)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if !reflect.DeepEqual(ctx, tt.want) {
+				t.Errorf("Set() = %+v, want %+v", ctx, tt.want)
+			}
+		})
+	}
+}
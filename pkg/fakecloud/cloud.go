@@ -0,0 +1,220 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakecloud
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Cloud is a fake OpenStack cloud serving the subset of the keystone, octavia, nova, neutron and glance APIs used by
+// osctl. The keystone API is served under /identity and the other services under /<region>/<service type>.
+type Cloud struct {
+	mu      sync.Mutex
+	fixture *Fixture
+	tokens  map[string]*token
+	serial  int
+}
+
+// token is an issued keystone token.
+type token struct {
+	user      *User
+	project   *Project
+	methods   []string
+	issuedAt  time.Time
+	expiresAt time.Time
+}
+
+// New returns a fake cloud in the initial state of the fixture.
+func New(f *Fixture) *Cloud {
+	return &Cloud{
+		fixture: f,
+		tokens:  make(map[string]*token),
+	}
+}
+
+// AuthURL returns the keystone URL of the fake cloud served at the base URL, e.g. http://127.0.0.1:8770.
+func AuthURL(baseURL string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/identity/v3"
+}
+
+func (c *Cloud) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Openstack-Request-Id", newRequestID())
+
+	c.mu.Lock()
+	latency, fault := c.matchFault(r)
+	c.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(latency):
+		}
+	}
+	if fault != nil && fault.Status != 0 {
+		if fault.RetryAfter > 0 {
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", fault.RetryAfter))
+		}
+		writeError(w, fault.Status, "Injected fault")
+		return
+	}
+
+	parts := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 3)
+	if parts[0] == "identity" {
+		c.serveKeystone(w, r, subPath(parts, 1))
+		return
+	}
+	if len(parts) < 2 {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	region, ok := c.fixture.Regions[parts[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Region %s not found", parts[0]))
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "The request you have made requires authentication.")
+		return
+	}
+
+	if !region.hasService(parts[1]) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Service %s not found", parts[1]))
+		return
+	}
+
+	path := subPath(parts, 2)
+	switch parts[1] {
+	case "load-balancer":
+		c.serveOctavia(w, r, region, path)
+	case "compute":
+		c.serveNova(w, r, region, path)
+	case "network":
+		c.serveNeutron(w, r, region, path)
+	case "image":
+		c.serveGlance(w, r, region, path)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Service %s not found", parts[1]))
+	}
+}
+
+// subPath returns the path after the first n parts.
+func subPath(parts []string, n int) string {
+	if len(parts) <= n {
+		return "/"
+	}
+	return "/" + strings.Join(parts[n:], "/")
+}
+
+// matchFault returns the latency of the request and the first matching fault that still fails requests.
+func (c *Cloud) matchFault(r *http.Request) (time.Duration, *Fault) {
+	latency := c.fixture.Latency.Duration
+
+	for _, fault := range c.fixture.Faults {
+		if fault.Method != "" && !strings.EqualFold(fault.Method, r.Method) {
+			continue
+		}
+		if !fault.path.MatchString(r.URL.Path) {
+			continue
+		}
+		if fault.Times > 0 && fault.hits >= fault.Times {
+			continue
+		}
+		fault.hits++
+		log.WithFields(log.Fields{"method": r.Method, "path": r.URL.Path, "status": fault.Status}).Info("Injecting fault")
+		return latency + fault.Latency.Duration, fault
+	}
+
+	return latency, nil
+}
+
+// authorized returns true if the request has a valid token.
+func (c *Cloud) authorized(r *http.Request) bool {
+	t, ok := c.tokens[r.Header.Get("X-Auth-Token")]
+	return ok && time.Now().Before(t.expiresAt)
+}
+
+// newID returns a unique ID with the prefix.
+func (c *Cloud) newID(prefix string) string {
+	c.serial++
+	return fmt.Sprintf("%s-%d", prefix, c.serial)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("req-%s-%s-%s-%s-%s", h[:8], h[8:12], h[12:16], h[16:20], h[20:])
+}
+
+// baseURL returns the URL the client used to reach the cloud.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
+
+// regionNames returns the sorted region names.
+func (c *Cloud) regionNames() []string {
+	var names []string
+	for name := range c.fixture.Regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    status,
+			"title":   http.StatusText(status),
+			"message": message,
+		},
+	})
+}
+
+// jsonTime formats the time as the services without the timezone suffix, e.g. octavia.
+func jsonTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05")
+}
+
+// jsonTimeZ formats the time in RFC3339 in UTC, e.g. nova and glance.
+func jsonTimeZ(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
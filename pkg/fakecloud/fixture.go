@@ -0,0 +1,390 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakecloud
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Duration is a time.Duration in the Go format in the fixture, e.g. 1m30s.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalYAML parses the duration string.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// MarshalYAML writes the duration string.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// Fixture is the initial state and behaviour of the fake cloud.
+type Fixture struct {
+	Users    []User    `yaml:"users"`
+	Projects []Project `yaml:"projects"`
+	// Regions maps the region names to the resources in the region.
+	Regions map[string]*Region `yaml:"regions"`
+
+	// TokenTTL is how long the issued tokens are valid, 1 hour by default.
+	TokenTTL Duration `yaml:"token_ttl"`
+	// FailoverDuration is how long a load balancer stays in PENDING_UPDATE after a failover request, 5 seconds by
+	// default.
+	FailoverDuration Duration `yaml:"failover_duration"`
+	// Latency is added to every response.
+	Latency Duration `yaml:"latency"`
+	// Faults are the errors injected into the matching requests.
+	Faults []*Fault `yaml:"faults"`
+
+	// ComputeVersion and LoadBalancerVersion are the highest API versions of nova and octavia.
+	ComputeVersion      string `yaml:"compute_version"`
+	LoadBalancerVersion string `yaml:"load_balancer_version"`
+}
+
+// User is a keystone user, it can authenticate with the password or its application credentials.
+type User struct {
+	ID       string `yaml:"id"`
+	Name     string `yaml:"name"`
+	Password string `yaml:"password"`
+	Domain   string `yaml:"domain"`
+	// Projects are the IDs of the projects the user has roles on.
+	Projects               []string                `yaml:"projects"`
+	Roles                  []string                `yaml:"roles"`
+	ApplicationCredentials []ApplicationCredential `yaml:"application_credentials"`
+}
+
+// ApplicationCredential is a keystone application credential of a user, scoped to a project.
+type ApplicationCredential struct {
+	ID      string `yaml:"id"`
+	Name    string `yaml:"name"`
+	Secret  string `yaml:"secret"`
+	Project string `yaml:"project"`
+}
+
+// Project is a keystone project.
+type Project struct {
+	ID     string `yaml:"id"`
+	Name   string `yaml:"name"`
+	Domain string `yaml:"domain"`
+}
+
+// Region contains the resources of a region.
+type Region struct {
+	// Services are the types of the services with endpoints in the region besides keystone, e.g. load-balancer, all of
+	// them by default.
+	Services []string `yaml:"services"`
+
+	LoadBalancers []*LoadBalancer `yaml:"loadbalancers"`
+	Images        []*Image        `yaml:"images"`
+	// Servers are the nova VMs besides the ones of the amphorae, which are created from the load balancers.
+	Servers []*Server `yaml:"servers"`
+}
+
+// LoadBalancer is an octavia load balancer with its sub-resources.
+type LoadBalancer struct {
+	ID                 string   `yaml:"id"`
+	Name               string   `yaml:"name"`
+	ProjectID          string   `yaml:"project_id"`
+	VipAddress         string   `yaml:"vip_address"`
+	VipPortID          string   `yaml:"vip_port_id"`
+	ProvisioningStatus string   `yaml:"provisioning_status"`
+	OperatingStatus    string   `yaml:"operating_status"`
+	FlavorID           string   `yaml:"flavor_id"`
+	Tags               []string `yaml:"tags"`
+	// SecurityGroups are the security groups of the vip and vrrp ports.
+	SecurityGroups []string `yaml:"security_groups"`
+
+	Listeners []*Listener `yaml:"listeners"`
+	Pools     []*Pool     `yaml:"pools"`
+	Amphorae  []*Amphora  `yaml:"amphorae"`
+
+	// pendingUntil is when the load balancer goes back to ACTIVE after a failover.
+	pendingUntil time.Time
+}
+
+// Listener is an octavia listener.
+type Listener struct {
+	ID            string `yaml:"id"`
+	Name          string `yaml:"name"`
+	Protocol      string `yaml:"protocol"`
+	ProtocolPort  int    `yaml:"protocol_port"`
+	DefaultPoolID string `yaml:"default_pool_id"`
+}
+
+// Pool is an octavia pool, it's shared by the load balancer if it doesn't belong to a listener.
+type Pool struct {
+	ID         string    `yaml:"id"`
+	Name       string    `yaml:"name"`
+	Protocol   string    `yaml:"protocol"`
+	ListenerID string    `yaml:"listener_id"`
+	Members    []*Member `yaml:"members"`
+}
+
+// Member is a member of an octavia pool.
+type Member struct {
+	ID           string `yaml:"id"`
+	Address      string `yaml:"address"`
+	ProtocolPort int    `yaml:"protocol_port"`
+}
+
+// Amphora is an octavia amphora, the nova VM and the vrrp port are created from it.
+type Amphora struct {
+	ID         string `yaml:"id"`
+	ComputeID  string `yaml:"compute_id"`
+	Role       string `yaml:"role"`
+	VRRPPortID string `yaml:"vrrp_port_id"`
+	ImageID    string `yaml:"image_id"`
+}
+
+// Image is a glance image.
+type Image struct {
+	ID        string    `yaml:"id"`
+	Name      string    `yaml:"name"`
+	Tags      []string  `yaml:"tags"`
+	CreatedAt time.Time `yaml:"created_at"`
+}
+
+// Server is a nova VM.
+type Server struct {
+	ID      string `yaml:"id"`
+	Name    string `yaml:"name"`
+	ImageID string `yaml:"image_id"`
+	Status  string `yaml:"status"`
+}
+
+// Fault is an error injected into the requests whose method and path match.
+type Fault struct {
+	// Method matches all the methods if empty.
+	Method string `yaml:"method"`
+	// Path is a regular expression matched against the request path, e.g. /load-balancer/v2.0/lbaas/loadbalancers$.
+	Path string `yaml:"path"`
+	// Status is the status code of the response, the request is only delayed if it's 0.
+	Status int `yaml:"status"`
+	// Times is how many matching requests fail, 0 means all of them.
+	Times int `yaml:"times"`
+	// RetryAfter is the Retry-After header of the response in seconds.
+	RetryAfter int `yaml:"retry_after"`
+	// Latency is added to the matching requests.
+	Latency Duration `yaml:"latency"`
+
+	path *regexp.Regexp
+	hits int
+}
+
+// ParseFixture parses the YAML fixture and fills the defaults.
+func ParseFixture(data []byte) (*Fixture, error) {
+	f := &Fixture{}
+	if err := yaml.UnmarshalStrict(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture: %v", err)
+	}
+	if err := f.setDefaults(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// LoadFixture reads the YAML fixture file.
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFixture(data)
+}
+
+// DefaultFixture returns the built-in fixture, a region with two load balancers and an amphora image newer than
+// the one of their amphorae.
+func DefaultFixture() *Fixture {
+	f, err := ParseFixture([]byte(defaultFixture))
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func (f *Fixture) setDefaults() error {
+	if f.TokenTTL.Duration == 0 {
+		f.TokenTTL.Duration = time.Hour
+	}
+	if f.FailoverDuration.Duration == 0 {
+		f.FailoverDuration.Duration = 5 * time.Second
+	}
+	if f.ComputeVersion == "" {
+		f.ComputeVersion = "2.79"
+	}
+	if f.LoadBalancerVersion == "" {
+		f.LoadBalancerVersion = "2.13"
+	}
+
+	for i := range f.Users {
+		setDefault(&f.Users[i].Domain, "default")
+		setDefault(&f.Users[i].ID, f.Users[i].Name)
+	}
+	for i := range f.Projects {
+		setDefault(&f.Projects[i].Domain, "default")
+		setDefault(&f.Projects[i].ID, f.Projects[i].Name)
+	}
+
+	for name, region := range f.Regions {
+		if region == nil {
+			return fmt.Errorf("region %s is empty", name)
+		}
+		for _, lb := range region.LoadBalancers {
+			if lb.ID == "" {
+				return fmt.Errorf("load balancer without ID in region %s", name)
+			}
+			setDefault(&lb.ProvisioningStatus, "ACTIVE")
+			setDefault(&lb.OperatingStatus, "ONLINE")
+			setDefault(&lb.VipPortID, "port-vip-"+lb.ID)
+			for _, amp := range lb.Amphorae {
+				setDefault(&amp.ComputeID, "vm-"+amp.ID)
+				setDefault(&amp.VRRPPortID, "port-vrrp-"+amp.ID)
+				setDefault(&amp.Role, "STANDALONE")
+			}
+		}
+		for _, server := range region.Servers {
+			setDefault(&server.Status, "ACTIVE")
+		}
+	}
+
+	for _, fault := range f.Faults {
+		re, err := regexp.Compile(fault.Path)
+		if err != nil {
+			return fmt.Errorf("invalid fault path %q: %v", fault.Path, err)
+		}
+		fault.path = re
+	}
+
+	return nil
+}
+
+// hasService returns true if the service type has endpoints in the region.
+func (r *Region) hasService(serviceType string) bool {
+	if len(r.Services) == 0 {
+		return true
+	}
+	for _, t := range r.Services {
+		if t == serviceType {
+			return true
+		}
+	}
+	return false
+}
+
+func setDefault(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+const defaultFixture = `
+users:
+- name: admin
+  password: password
+  projects: [admin]
+  roles: [admin, member]
+  application_credentials:
+  - id: appcred
+    name: osctl
+    secret: secret
+    project: admin
+projects:
+- name: admin
+- name: demo
+regions:
+  RegionOne:
+    images:
+    - id: amphora-image-old
+      name: amphora-x64-haproxy-old
+      tags: [amphora]
+      created_at: 2020-01-01T00:00:00Z
+    - id: amphora-image-new
+      name: amphora-x64-haproxy
+      tags: [amphora]
+      created_at: 2020-06-01T00:00:00Z
+    loadbalancers:
+    - id: lb-web
+      name: web
+      project_id: demo
+      vip_address: 10.0.0.10
+      tags: [production]
+      security_groups: [sg-lb-mgmt]
+      listeners:
+      - id: listener-http
+        name: http
+        protocol: HTTP
+        protocol_port: 80
+        default_pool_id: pool-web
+      pools:
+      - id: pool-web
+        protocol: HTTP
+        listener_id: listener-http
+        members:
+        - id: member-web-1
+          address: 10.0.0.21
+          protocol_port: 8080
+        - id: member-web-2
+          address: 10.0.0.22
+          protocol_port: 8080
+      amphorae:
+      - id: amp-web-1
+        role: MASTER
+        image_id: amphora-image-old
+      - id: amp-web-2
+        role: BACKUP
+        image_id: amphora-image-old
+    - id: lb-db
+      name: db
+      project_id: demo
+      vip_address: 10.0.0.11
+      listeners:
+      - id: listener-mysql
+        protocol: TCP
+        protocol_port: 3306
+        default_pool_id: pool-mysql
+      pools:
+      - id: pool-mysql
+        protocol: TCP
+        listener_id: listener-mysql
+        members:
+        - id: member-db-1
+          address: 10.0.0.31
+          protocol_port: 3306
+      amphorae:
+      - id: amp-db-1
+        image_id: amphora-image-new
+`
+
+// DefaultFixtureYAML returns the built-in fixture in YAML, a starting point for custom fixtures.
+func DefaultFixtureYAML() string {
+	return strings.TrimPrefix(defaultFixture, "\n")
+}
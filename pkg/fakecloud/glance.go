@@ -0,0 +1,81 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakecloud
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func (c *Cloud) serveGlance(w http.ResponseWriter, r *http.Request, region *Region, path string) {
+	if path != "/v2/images" || r.Method != http.MethodGet {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	query := r.URL.Query()
+	var images []*Image
+	for _, image := range region.Images {
+		if hasTags(image.Tags, query["tag"]) {
+			images = append(images, image)
+		}
+	}
+
+	// Only sorting by the creation time is supported.
+	desc := strings.HasSuffix(query.Get("sort"), ":desc") || query.Get("sort_dir") == "desc"
+	sort.SliceStable(images, func(i, j int) bool {
+		if desc {
+			return images[i].CreatedAt.After(images[j].CreatedAt)
+		}
+		return images[i].CreatedAt.Before(images[j].CreatedAt)
+	})
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 && limit < len(images) {
+		images = images[:limit]
+	}
+
+	var result []interface{}
+	for _, image := range images {
+		tags := image.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		result = append(result, map[string]interface{}{
+			"id":               image.ID,
+			"name":             image.Name,
+			"status":           "active",
+			"visibility":       "public",
+			"tags":             tags,
+			"container_format": "bare",
+			"disk_format":      "qcow2",
+			"created_at":       jsonTimeZ(image.CreatedAt),
+			"updated_at":       jsonTimeZ(image.CreatedAt),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"images": emptyIfNil(result)})
+}
+
+// latestImage returns the newest image with the tag.
+func latestImage(region *Region, tag string) *Image {
+	var latest *Image
+	for _, image := range region.Images {
+		if hasTags(image.Tags, []string{tag}) && (latest == nil || image.CreatedAt.After(latest.CreatedAt)) {
+			latest = image
+		}
+	}
+	return latest
+}
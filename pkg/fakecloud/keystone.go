@@ -0,0 +1,310 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakecloud
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type authDomain struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type authUser struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Password string     `json:"password"`
+	Domain   authDomain `json:"domain"`
+}
+
+// authRequest is the body of the keystone token request.
+type authRequest struct {
+	Auth struct {
+		Identity struct {
+			Methods  []string `json:"methods"`
+			Password struct {
+				User authUser `json:"user"`
+			} `json:"password"`
+			ApplicationCredential struct {
+				ID     string   `json:"id"`
+				Name   string   `json:"name"`
+				Secret string   `json:"secret"`
+				User   authUser `json:"user"`
+			} `json:"application_credential"`
+			Token struct {
+				ID string `json:"id"`
+			} `json:"token"`
+		} `json:"identity"`
+		Scope struct {
+			Project *struct {
+				ID     string     `json:"id"`
+				Name   string     `json:"name"`
+				Domain authDomain `json:"domain"`
+			} `json:"project"`
+			Domain *authDomain     `json:"domain"`
+			System map[string]bool `json:"system"`
+		} `json:"scope"`
+	} `json:"auth"`
+}
+
+func (c *Cloud) serveKeystone(w http.ResponseWriter, r *http.Request, path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case path == "/" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusMultipleChoices, map[string]interface{}{
+			"versions": map[string]interface{}{
+				"values": []interface{}{map[string]interface{}{
+					"id":     "v3.14",
+					"status": "stable",
+					"links":  []interface{}{map[string]string{"rel": "self", "href": baseURL(r) + "/identity/v3/"}},
+				}},
+			},
+		})
+	case path == "/v3/auth/tokens" && r.Method == http.MethodPost:
+		c.issueToken(w, r)
+	case path == "/v3/projects" && r.Method == http.MethodGet:
+		if !c.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "The request you have made requires authentication.")
+			return
+		}
+		var projects []interface{}
+		for _, p := range c.fixture.Projects {
+			projects = append(projects, map[string]interface{}{
+				"id":        p.ID,
+				"name":      p.Name,
+				"domain_id": p.Domain,
+				"enabled":   true,
+				"is_domain": false,
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"projects": projects,
+			"links":    map[string]interface{}{"self": baseURL(r) + r.URL.Path, "next": nil},
+		})
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// issueToken authenticates the user of the token request and returns a token with the catalog.
+func (c *Cloud) issueToken(w http.ResponseWriter, r *http.Request) {
+	var req authRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid token request")
+		return
+	}
+	identity := req.Auth.Identity
+	if len(identity.Methods) != 1 {
+		writeError(w, http.StatusBadRequest, "Exactly one auth method is supported")
+		return
+	}
+
+	var user *User
+	var project *Project
+	switch identity.Methods[0] {
+	case "password":
+		user = c.findUser(identity.Password.User)
+		if user == nil || user.Password != identity.Password.User.Password {
+			user = nil
+		}
+	case "application_credential":
+		user, project = c.findApplicationCredential(identity.ApplicationCredential.ID, identity.ApplicationCredential.Name,
+			identity.ApplicationCredential.Secret, identity.ApplicationCredential.User)
+	case "token":
+		if t, ok := c.tokens[identity.Token.ID]; ok && time.Now().Before(t.expiresAt) {
+			user = t.user
+		}
+	}
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "The request you have made requires authentication.")
+		return
+	}
+
+	if scope := req.Auth.Scope.Project; scope != nil && project == nil {
+		project = c.findProject(scope.ID, scope.Name)
+		if project == nil || !hasProject(user, project.ID) {
+			writeError(w, http.StatusUnauthorized, "The user doesn't have access to the project.")
+			return
+		}
+	}
+
+	now := time.Now()
+	t := &token{
+		user:      user,
+		project:   project,
+		methods:   identity.Methods,
+		issuedAt:  now,
+		expiresAt: now.Add(c.fixture.TokenTTL.Duration),
+	}
+	id := newTokenID()
+	c.tokens[id] = t
+
+	body := map[string]interface{}{
+		"methods":    t.methods,
+		"issued_at":  t.issuedAt.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		"expires_at": t.expiresAt.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		"user": map[string]interface{}{
+			"id":     user.ID,
+			"name":   user.Name,
+			"domain": map[string]string{"id": user.Domain, "name": user.Domain},
+		},
+	}
+	switch {
+	case project != nil:
+		body["project"] = map[string]interface{}{
+			"id":     project.ID,
+			"name":   project.Name,
+			"domain": map[string]string{"id": project.Domain, "name": project.Domain},
+		}
+	case req.Auth.Scope.Domain != nil:
+		body["domain"] = map[string]string{"id": user.Domain, "name": user.Domain}
+	case req.Auth.Scope.System != nil:
+		body["system"] = map[string]bool{"all": true}
+	}
+	if project != nil || req.Auth.Scope.Domain != nil || req.Auth.Scope.System != nil {
+		var roles []interface{}
+		for _, role := range user.Roles {
+			roles = append(roles, map[string]string{"id": role, "name": role})
+		}
+		body["roles"] = roles
+		body["catalog"] = c.catalog(baseURL(r))
+	}
+
+	w.Header().Set("X-Subject-Token", id)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"token": body})
+}
+
+// catalog returns the service catalog with the same endpoint for all the interfaces.
+func (c *Cloud) catalog(base string) []interface{} {
+	services := []struct {
+		serviceType string
+		name        string
+		path        string
+	}{
+		{"identity", "keystone", "identity/v3/"},
+		{"load-balancer", "octavia", "load-balancer/"},
+		{"compute", "nova", "compute/v2.1/"},
+		{"network", "neutron", "network/"},
+		{"image", "glance", "image/"},
+	}
+
+	var catalog []interface{}
+	for _, service := range services {
+		var endpoints []interface{}
+		for _, region := range c.regionNames() {
+			if service.serviceType != "identity" && !c.fixture.Regions[region].hasService(service.serviceType) {
+				continue
+			}
+			url := base + "/" + region + "/" + service.path
+			if service.serviceType == "identity" {
+				url = base + "/" + service.path
+			}
+			for _, iface := range []string{"public", "internal", "admin"} {
+				endpoints = append(endpoints, map[string]string{
+					"id":        service.name + "-" + region + "-" + iface,
+					"interface": iface,
+					"region":    region,
+					"region_id": region,
+					"url":       url,
+				})
+			}
+		}
+		catalog = append(catalog, map[string]interface{}{
+			"id":        service.name,
+			"name":      service.name,
+			"type":      service.serviceType,
+			"endpoints": endpoints,
+		})
+	}
+
+	return catalog
+}
+
+// findUser returns the user by ID, or by name and domain.
+func (c *Cloud) findUser(u authUser) *User {
+	for i := range c.fixture.Users {
+		user := &c.fixture.Users[i]
+		if u.ID != "" && u.ID == user.ID {
+			return user
+		}
+		if u.Name != "" && u.Name == user.Name && sameDomain(u.Domain, user.Domain) {
+			return user
+		}
+	}
+	return nil
+}
+
+// findApplicationCredential returns the user and project of the application credential.
+func (c *Cloud) findApplicationCredential(id, name, secret string, u authUser) (*User, *Project) {
+	for i := range c.fixture.Users {
+		user := &c.fixture.Users[i]
+		for _, cred := range user.ApplicationCredentials {
+			if id != "" && id != cred.ID {
+				continue
+			}
+			if id == "" && (name != cred.Name || c.findUser(u) != user) {
+				continue
+			}
+			if secret != cred.Secret {
+				return nil, nil
+			}
+			return user, c.findProject(cred.Project, cred.Project)
+		}
+	}
+	return nil, nil
+}
+
+// findProject returns the project by ID or name.
+func (c *Cloud) findProject(id, name string) *Project {
+	for i := range c.fixture.Projects {
+		p := &c.fixture.Projects[i]
+		if (id != "" && id == p.ID) || (name != "" && name == p.Name) {
+			return p
+		}
+	}
+	return nil
+}
+
+func hasProject(user *User, projectID string) bool {
+	for _, id := range user.Projects {
+		if id == projectID {
+			return true
+		}
+	}
+	return false
+}
+
+// sameDomain returns true if the domain in the request refers to the domain, the domain of the fixture is used as
+// both the ID and the name.
+func sameDomain(d authDomain, domain string) bool {
+	if d.ID == "" && d.Name == "" {
+		return domain == "default"
+	}
+	return strings.EqualFold(d.ID, domain) || strings.EqualFold(d.Name, domain)
+}
+
+func newTokenID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "gAAAAA" + hex.EncodeToString(b)
+}
@@ -0,0 +1,66 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func (c *Cloud) serveNeutron(w http.ResponseWriter, r *http.Request, region *Region, path string) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case len(parts) == 3 && parts[0] == "v2.0" && parts[1] == "ports" && r.Method == http.MethodGet:
+		lb := findPortOwner(region, parts[2])
+		if lb == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Port %s could not be found.", parts[2]))
+			return
+		}
+		groups := lb.SecurityGroups
+		if groups == nil {
+			groups = []string{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"port": map[string]interface{}{
+				"id":              parts[2],
+				"name":            "octavia-lb-" + lb.ID,
+				"status":          "ACTIVE",
+				"project_id":      lb.ProjectID,
+				"admin_state_up":  true,
+				"security_groups": groups,
+				"fixed_ips":       []interface{}{},
+			},
+		})
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// findPortOwner returns the load balancer of the vip or vrrp port.
+func findPortOwner(region *Region, id string) *LoadBalancer {
+	for _, lb := range region.LoadBalancers {
+		if lb.VipPortID == id {
+			return lb
+		}
+		for _, amp := range lb.Amphorae {
+			if amp.VRRPPortID == id {
+				return lb
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func (c *Cloud) serveNova(w http.ResponseWriter, r *http.Request, region *Region, path string) {
+	for _, lb := range region.LoadBalancers {
+		c.refreshLoadBalancer(region, lb)
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if parts[0] != "v2.1" {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"version": map[string]string{
+				"id":          "v2.1",
+				"status":      "CURRENT",
+				"version":     c.fixture.ComputeVersion,
+				"min_version": "2.1",
+			},
+		})
+	case len(parts) == 3 && parts[1] == "servers" && r.Method == http.MethodGet:
+		server := findServer(region, parts[2])
+		if server == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Instance %s could not be found.", parts[2]))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"server": serverJSON(server)})
+	case len(parts) == 2 && parts[1] == "os-server-groups" && r.Method == http.MethodGet:
+		var groups []interface{}
+		for _, lb := range region.LoadBalancers {
+			if len(lb.Amphorae) == 0 {
+				continue
+			}
+			var members []string
+			for _, amp := range lb.Amphorae {
+				members = append(members, amp.ComputeID)
+			}
+			groups = append(groups, map[string]interface{}{
+				"id":       "server-group-" + lb.ID,
+				"name":     "octavia-lb-" + lb.ID,
+				"policies": []string{"anti-affinity"},
+				"members":  members,
+				"metadata": map[string]string{},
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"server_groups": emptyIfNil(groups)})
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// findServer returns the VM of the fixture or of an amphora.
+func findServer(region *Region, id string) *Server {
+	for _, server := range region.Servers {
+		if server.ID == id {
+			return server
+		}
+	}
+	for _, lb := range region.LoadBalancers {
+		for _, amp := range lb.Amphorae {
+			if amp.ComputeID == id {
+				return &Server{ID: amp.ComputeID, Name: "amphora-" + amp.ID, ImageID: amp.ImageID, Status: "ACTIVE"}
+			}
+		}
+	}
+	return nil
+}
+
+func serverJSON(s *Server) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        s.ID,
+		"name":      s.Name,
+		"status":    s.Status,
+		"image":     map[string]string{"id": s.ImageID},
+		"flavor":    map[string]string{"id": "amphora"},
+		"addresses": map[string]interface{}{},
+		"metadata":  map[string]string{},
+		"created":   jsonTimeZ(epoch),
+		"updated":   jsonTimeZ(epoch),
+	}
+}
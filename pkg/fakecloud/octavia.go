@@ -0,0 +1,353 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// epoch is the creation time of the resources in the fixture.
+var epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (c *Cloud) serveOctavia(w http.ResponseWriter, r *http.Request, region *Region, path string) {
+	for _, lb := range region.LoadBalancers {
+		c.refreshLoadBalancer(region, lb)
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case path == "/" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"versions": []interface{}{
+				map[string]string{"id": "v2.0", "status": "SUPPORTED"},
+				map[string]string{"id": "v" + c.fixture.LoadBalancerVersion, "status": "CURRENT"},
+			},
+		})
+	case len(parts) == 3 && parts[1] == "lbaas" && parts[2] == "loadbalancers" && r.Method == http.MethodGet:
+		c.listLoadBalancers(w, r, region)
+	case len(parts) == 4 && parts[1] == "lbaas" && parts[2] == "loadbalancers" && r.Method == http.MethodGet:
+		lb := findLoadBalancer(region, parts[3])
+		if lb == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Load Balancer %s not found.", parts[3]))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"loadbalancer": loadBalancerJSON(lb)})
+	case len(parts) == 5 && parts[1] == "lbaas" && parts[2] == "loadbalancers" && parts[4] == "failover" && r.Method == http.MethodPut:
+		c.failoverLoadBalancer(w, region, parts[3])
+	case len(parts) == 4 && parts[1] == "lbaas" && parts[2] == "listeners" && r.Method == http.MethodGet:
+		lb, listener := findListener(region, parts[3])
+		if listener == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Listener %s not found.", parts[3]))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"listener": listenerJSON(lb, listener)})
+	case len(parts) == 3 && parts[1] == "lbaas" && parts[2] == "pools" && r.Method == http.MethodGet:
+		var pools []interface{}
+		for _, lb := range region.LoadBalancers {
+			if id := r.URL.Query().Get("loadbalancer_id"); id != "" && id != lb.ID {
+				continue
+			}
+			for _, pool := range lb.Pools {
+				pools = append(pools, poolJSON(lb, pool))
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"pools": emptyIfNil(pools)})
+	case len(parts) == 4 && parts[1] == "lbaas" && parts[2] == "pools" && r.Method == http.MethodGet:
+		lb, pool := findPool(region, parts[3])
+		if pool == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Pool %s not found.", parts[3]))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"pool": poolJSON(lb, pool)})
+	case len(parts) == 5 && parts[1] == "lbaas" && parts[2] == "pools" && parts[4] == "members" && r.Method == http.MethodGet:
+		lb, pool := findPool(region, parts[3])
+		if pool == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Pool %s not found.", parts[3]))
+			return
+		}
+		var members []interface{}
+		for _, m := range pool.Members {
+			members = append(members, map[string]interface{}{
+				"id":                  m.ID,
+				"address":             m.Address,
+				"protocol_port":       m.ProtocolPort,
+				"project_id":          lb.ProjectID,
+				"admin_state_up":      true,
+				"provisioning_status": "ACTIVE",
+				"operating_status":    "ONLINE",
+				"weight":              1,
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"members": emptyIfNil(members)})
+	case len(parts) == 3 && parts[1] == "octavia" && parts[2] == "amphorae" && r.Method == http.MethodGet:
+		var amphorae []interface{}
+		for _, lb := range region.LoadBalancers {
+			if id := r.URL.Query().Get("loadbalancer_id"); id != "" && id != lb.ID {
+				continue
+			}
+			for _, amp := range lb.Amphorae {
+				amphorae = append(amphorae, amphoraJSON(lb, amp))
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"amphorae": emptyIfNil(amphorae)})
+	case len(parts) == 4 && parts[1] == "octavia" && parts[2] == "amphorae" && r.Method == http.MethodGet:
+		lb, amp := findAmphora(region, parts[3])
+		if amp == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Amphora %s not found.", parts[3]))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"amphora": amphoraJSON(lb, amp)})
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (c *Cloud) listLoadBalancers(w http.ResponseWriter, r *http.Request, region *Region) {
+	query := r.URL.Query()
+
+	var tags []string
+	for _, v := range query["tags"] {
+		tags = append(tags, strings.Split(v, ",")...)
+	}
+
+	var lbs []interface{}
+	for _, lb := range region.LoadBalancers {
+		if project := query.Get("project_id"); project != "" && project != lb.ProjectID {
+			continue
+		}
+		if !hasTags(lb.Tags, tags) {
+			continue
+		}
+		lbs = append(lbs, loadBalancerJSON(lb))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"loadbalancers": emptyIfNil(lbs)})
+}
+
+// failoverLoadBalancer puts the load balancer into PENDING_UPDATE, it goes back to ACTIVE with new amphorae after the
+// failover duration of the fixture.
+func (c *Cloud) failoverLoadBalancer(w http.ResponseWriter, region *Region, id string) {
+	lb := findLoadBalancer(region, id)
+	if lb == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Load Balancer %s not found.", id))
+		return
+	}
+	if lb.ProvisioningStatus != "ACTIVE" {
+		writeError(w, http.StatusConflict, fmt.Sprintf("Invalid state %s of loadbalancer resource %s", lb.ProvisioningStatus, id))
+		return
+	}
+
+	lb.ProvisioningStatus = "PENDING_UPDATE"
+	lb.pendingUntil = time.Now().Add(c.fixture.FailoverDuration.Duration)
+	log.WithFields(log.Fields{"loadbalancer": id}).Info("Load balancer failover started")
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// refreshLoadBalancer completes the failover of the load balancer if its duration has passed. The amphorae are
+// rebuilt with the latest amphora image.
+func (c *Cloud) refreshLoadBalancer(region *Region, lb *LoadBalancer) {
+	if lb.pendingUntil.IsZero() || time.Now().Before(lb.pendingUntil) {
+		return
+	}
+
+	image := latestImage(region, "amphora")
+	for _, amp := range lb.Amphorae {
+		amp.ComputeID = c.newID("vm-" + amp.ID)
+		if image != nil {
+			amp.ImageID = image.ID
+		}
+	}
+	lb.ProvisioningStatus = "ACTIVE"
+	lb.pendingUntil = time.Time{}
+	log.WithFields(log.Fields{"loadbalancer": lb.ID}).Info("Load balancer failover completed")
+}
+
+func findLoadBalancer(region *Region, id string) *LoadBalancer {
+	for _, lb := range region.LoadBalancers {
+		if lb.ID == id {
+			return lb
+		}
+	}
+	return nil
+}
+
+func findListener(region *Region, id string) (*LoadBalancer, *Listener) {
+	for _, lb := range region.LoadBalancers {
+		for _, listener := range lb.Listeners {
+			if listener.ID == id {
+				return lb, listener
+			}
+		}
+	}
+	return nil, nil
+}
+
+func findPool(region *Region, id string) (*LoadBalancer, *Pool) {
+	for _, lb := range region.LoadBalancers {
+		for _, pool := range lb.Pools {
+			if pool.ID == id {
+				return lb, pool
+			}
+		}
+	}
+	return nil, nil
+}
+
+func findAmphora(region *Region, id string) (*LoadBalancer, *Amphora) {
+	for _, lb := range region.LoadBalancers {
+		for _, amp := range lb.Amphorae {
+			if amp.ID == id {
+				return lb, amp
+			}
+		}
+	}
+	return nil, nil
+}
+
+// hasTags returns true if all the wanted tags are in tags.
+func hasTags(tags, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, t := range tags {
+			if t == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func idRefs(ids []string) []interface{} {
+	refs := []interface{}{}
+	for _, id := range ids {
+		refs = append(refs, map[string]string{"id": id})
+	}
+	return refs
+}
+
+func emptyIfNil(v []interface{}) []interface{} {
+	if v == nil {
+		return []interface{}{}
+	}
+	return v
+}
+
+func loadBalancerJSON(lb *LoadBalancer) map[string]interface{} {
+	var listenerIDs, poolIDs []string
+	for _, l := range lb.Listeners {
+		listenerIDs = append(listenerIDs, l.ID)
+	}
+	for _, p := range lb.Pools {
+		poolIDs = append(poolIDs, p.ID)
+	}
+
+	tags := lb.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return map[string]interface{}{
+		"id":                  lb.ID,
+		"name":                lb.Name,
+		"description":         "",
+		"project_id":          lb.ProjectID,
+		"vip_address":         lb.VipAddress,
+		"vip_port_id":         lb.VipPortID,
+		"vip_subnet_id":       "subnet-vip",
+		"vip_network_id":      "network-vip",
+		"provisioning_status": lb.ProvisioningStatus,
+		"operating_status":    lb.OperatingStatus,
+		"admin_state_up":      true,
+		"provider":            "amphora",
+		"flavor_id":           lb.FlavorID,
+		"tags":                tags,
+		"listeners":           idRefs(listenerIDs),
+		"pools":               idRefs(poolIDs),
+		"created_at":          jsonTime(epoch),
+		"updated_at":          jsonTime(epoch),
+	}
+}
+
+func listenerJSON(lb *LoadBalancer, l *Listener) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                  l.ID,
+		"name":                l.Name,
+		"project_id":          lb.ProjectID,
+		"protocol":            l.Protocol,
+		"protocol_port":       l.ProtocolPort,
+		"default_pool_id":     l.DefaultPoolID,
+		"loadbalancers":       idRefs([]string{lb.ID}),
+		"admin_state_up":      true,
+		"provisioning_status": lb.ProvisioningStatus,
+		"operating_status":    "ONLINE",
+	}
+}
+
+func poolJSON(lb *LoadBalancer, p *Pool) map[string]interface{} {
+	var listenerIDs, memberIDs []string
+	if p.ListenerID != "" {
+		listenerIDs = append(listenerIDs, p.ListenerID)
+	}
+	for _, m := range p.Members {
+		memberIDs = append(memberIDs, m.ID)
+	}
+
+	return map[string]interface{}{
+		"id":                  p.ID,
+		"name":                p.Name,
+		"project_id":          lb.ProjectID,
+		"protocol":            p.Protocol,
+		"lb_algorithm":        "ROUND_ROBIN",
+		"loadbalancers":       idRefs([]string{lb.ID}),
+		"listeners":           idRefs(listenerIDs),
+		"members":             idRefs(memberIDs),
+		"admin_state_up":      true,
+		"provisioning_status": lb.ProvisioningStatus,
+		"operating_status":    "ONLINE",
+	}
+}
+
+func amphoraJSON(lb *LoadBalancer, amp *Amphora) map[string]interface{} {
+	status := "ALLOCATED"
+	if lb.ProvisioningStatus == "PENDING_UPDATE" {
+		status = "PENDING_UPDATE"
+	}
+
+	return map[string]interface{}{
+		"id":              amp.ID,
+		"loadbalancer_id": lb.ID,
+		"compute_id":      amp.ComputeID,
+		"role":            amp.Role,
+		"status":          status,
+		"vrrp_port_id":    amp.VRRPPortID,
+		"ha_port_id":      lb.VipPortID,
+		"ha_ip":           lb.VipAddress,
+		"image_id":        amp.ImageID,
+		"cert_busy":       false,
+		"cert_expiration": jsonTime(epoch.AddDate(2, 0, 0)),
+		"created_at":      jsonTime(epoch),
+		"updated_at":      jsonTime(epoch),
+	}
+}
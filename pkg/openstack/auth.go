@@ -0,0 +1,111 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+)
+
+// AuthOptions extends gophercloud.AuthOptions with the token scopes gophercloud doesn't support, e.g. a project
+// domain different from the user domain and the system scope. The user domain is set in the embedded DomainID or
+// DomainName.
+type AuthOptions struct {
+	gophercloud.AuthOptions
+
+	Scope AuthScope
+}
+
+// AuthScope defines the scope of the keystone token, at most one of project, domain and system should be set.
+type AuthScope struct {
+	ProjectID         string
+	ProjectName       string
+	ProjectDomainID   string
+	ProjectDomainName string
+	DomainID          string
+	DomainName        string
+	System            bool
+}
+
+// ToTokenV3CreateMap builds the token request body.
+func (opts *AuthOptions) ToTokenV3CreateMap(scope map[string]interface{}) (map[string]interface{}, error) {
+	return opts.AuthOptions.ToTokenV3CreateMap(scope)
+}
+
+// ToTokenV3ScopeMap builds the scope section of the token request body.
+func (opts *AuthOptions) ToTokenV3ScopeMap() (map[string]interface{}, error) {
+	scope := opts.Scope
+
+	switch {
+	case scope.System:
+		return map[string]interface{}{
+			"system": map[string]interface{}{"all": true},
+		}, nil
+	case scope.ProjectID != "":
+		return map[string]interface{}{
+			"project": map[string]interface{}{"id": scope.ProjectID},
+		}, nil
+	case scope.ProjectName != "":
+		domain := map[string]interface{}{"name": scope.ProjectDomainName}
+		if scope.ProjectDomainID != "" {
+			domain = map[string]interface{}{"id": scope.ProjectDomainID}
+		}
+		return map[string]interface{}{
+			"project": map[string]interface{}{"name": scope.ProjectName, "domain": domain},
+		}, nil
+	case scope.DomainID != "":
+		return map[string]interface{}{
+			"domain": map[string]interface{}{"id": scope.DomainID},
+		}, nil
+	case scope.DomainName != "":
+		return map[string]interface{}{
+			"domain": map[string]interface{}{"name": scope.DomainName},
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// CanReauth returns true if the credentials can be used to re-authenticate.
+func (opts *AuthOptions) CanReauth() bool {
+	return opts.AllowReauth
+}
+
+// authenticateV3 authenticates the provider client with the options. gophercloud only turns AllowReauth off in the
+// options of the re-authentication for its own option types, so the re-authentication is set up here the same way:
+// the new token is requested by a throw-away copy of the client with options that can't re-authenticate again.
+func authenticateV3(provider *gophercloud.ProviderClient, opts *AuthOptions) error {
+	once := *opts
+	once.AllowReauth = false
+	if err := openstack.AuthenticateV3(provider, &once, gophercloud.EndpointOpts{}); err != nil {
+		return err
+	}
+	if !opts.CanReauth() {
+		return nil
+	}
+
+	provider.ReauthFunc = func() error {
+		tac := *provider
+		tac.SetThrowaway(true)
+		tac.ReauthFunc = nil
+		tac.SetTokenAndAuthResult(nil)
+		if err := openstack.AuthenticateV3(&tac, &once, gophercloud.EndpointOpts{}); err != nil {
+			return err
+		}
+		provider.CopyTokenFrom(&tac)
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Replay modes.
+const (
+	// ReplayStrict requires the same URL and request body, every recorded interaction is replayed once.
+	ReplayStrict = "strict"
+	// ReplayLenient only requires the same method, path and query parameters in any order. The last matching
+	// interaction is replayed again once all of them are used, e.g. for longer polling.
+	ReplayLenient = "lenient"
+)
+
+// interaction is a recorded HTTP request and its response, one per cassette file.
+type interaction struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        string      `json:"body,omitempty"`
+}
+
+var (
+	cassettesMu sync.Mutex
+	// cassettes are shared by all the clients recording into or replaying from the same directory, e.g. the clients
+	// of several clouds.
+	cassettes = make(map[string]interface{})
+)
+
+// cassetteWriter numbers the cassette files written into a directory.
+type cassetteWriter struct {
+	dir string
+
+	mu     sync.Mutex
+	serial int
+}
+
+// recordTransport saves every interaction as a cassette file, with the tokens and credentials redacted. The bodies
+// which are not JSON can't be redacted, only their size and type are saved.
+type recordTransport struct {
+	next   http.RoundTripper
+	writer *cassetteWriter
+}
+
+// newRecordTransport returns a transport recording into the directory.
+func newRecordTransport(next http.RoundTripper, dir string) (http.RoundTripper, error) {
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+
+	writer, ok := cassettes[dir].(*cassetteWriter)
+	if !ok {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create cassette directory: %v", err)
+		}
+		writer = &cassetteWriter{dir: dir}
+		cassettes[dir] = writer
+	}
+
+	return &recordTransport{next: next, writer: writer}, nil
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = ioutil.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	for _, name := range sensitiveHeaders {
+		if header.Get(name) != "" {
+			header.Set(name, redacted)
+		}
+	}
+	i := interaction{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: redactBody(reqBody, req.Header.Get("Content-Type")),
+		Status:      resp.StatusCode,
+		Header:      header,
+		Body:        redactFullBody(body, resp.Header.Get("Content-Type")),
+	}
+	if err := t.writer.save(i); err != nil {
+		log.WithFields(log.Fields{"error": err, "url": i.URL}).Warn("Failed to record interaction")
+	}
+
+	return resp, nil
+}
+
+// save writes the interaction into the next cassette file.
+func (w *cassetteWriter) save(i interaction) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(i); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.serial++
+	name := fmt.Sprintf("%05d-%s.json", w.serial, strings.ToLower(i.Method))
+	w.mu.Unlock()
+
+	return ioutil.WriteFile(filepath.Join(w.dir, name), buf.Bytes(), 0600)
+}
+
+// replayTransport serves the recorded responses instead of sending the requests.
+type replayTransport struct {
+	strict bool
+
+	mu           sync.Mutex
+	interactions []interaction
+	used         []bool
+}
+
+// newReplayTransport returns the transport replaying the cassette files of the directory.
+func newReplayTransport(dir, mode string) (*replayTransport, error) {
+	switch mode {
+	case "", ReplayStrict, ReplayLenient:
+	default:
+		return nil, fmt.Errorf("invalid replay mode %s, expected strict or lenient", mode)
+	}
+
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+
+	if t, ok := cassettes[dir].(*replayTransport); ok {
+		return t, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no cassette file found in %s", dir)
+	}
+	sort.Strings(files)
+
+	t := &replayTransport{strict: mode != ReplayLenient}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var i interaction
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette file %s: %v", file, err)
+		}
+		t.interactions = append(t.interactions, i)
+	}
+	t.used = make([]bool, len(t.interactions))
+	cassettes[dir] = t
+
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	body := redactBody(reqBody, req.Header.Get("Content-Type"))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last := -1
+	for n, i := range t.interactions {
+		if !t.matches(i, req, body) {
+			continue
+		}
+		last = n
+		if t.used[n] {
+			continue
+		}
+		t.used[n] = true
+		return i.response(req), nil
+	}
+	if last >= 0 && !t.strict {
+		return t.interactions[last].response(req), nil
+	}
+
+	return nil, &replayMissError{method: req.Method, url: req.URL.String()}
+}
+
+// replayMissError is returned when there is no recorded interaction for the request, the request is not retried.
+type replayMissError struct {
+	method string
+	url    string
+}
+
+func (e *replayMissError) Error() string {
+	return fmt.Sprintf("no recorded interaction for %s %s", e.method, e.url)
+}
+
+// matches returns true if the interaction is a recording of the request.
+func (t *replayTransport) matches(i interaction, req *http.Request, body string) bool {
+	if i.Method != req.Method {
+		return false
+	}
+	if t.strict {
+		return i.URL == req.URL.String() && i.RequestBody == body
+	}
+
+	recorded, err := url.Parse(i.URL)
+	if err != nil {
+		return false
+	}
+	return recorded.Path == req.URL.Path && recorded.Query().Encode() == req.URL.Query().Encode()
+}
+
+// response returns the recorded response of the request.
+func (i interaction) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        i.Header.Clone(),
+		Body:          ioutil.NopCloser(strings.NewReader(i.Body)),
+		ContentLength: int64(len(i.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lingxiankong/openstackcli-go/pkg/fakecloud"
+)
+
+func TestRecordReplay(t *testing.T) {
+	f := fakecloud.DefaultFixture()
+	f.Users[0].Password = "s3cr3t-passw0rd"
+	server, cfg := newFakeCloud(t, f)
+	cfg.Password = "s3cr3t-passw0rd"
+
+	dir, err := ioutil.TempDir("", "cassettes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	recordCfg := cfg
+	recordCfg.Record = dir
+	client, err := NewOpenStack(ctx, recordCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := client.GetLoadBalancer(ctx, "lb-web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := client.Token()
+	// The replay must not reach the cloud.
+	server.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no cassette file recorded")
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{cfg.Password, token} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("cassette file %s contains the secret %s", filepath.Base(file), secret)
+			}
+		}
+	}
+
+	replayCfg := cfg
+	replayCfg.Replay = dir
+	client, err = NewOpenStack(ctx, replayCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := client.GetLoadBalancer(ctx, "lb-web")
+	if err != nil {
+		t.Fatalf("GetLoadBalancer() error = %v", err)
+	}
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("GetLoadBalancer() = %+v, want the recorded %+v", replayed, recorded)
+	}
+
+	// Every interaction is replayed once in the strict mode.
+	if _, err := client.GetLoadBalancer(ctx, "lb-web"); err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("GetLoadBalancer() error = %v, want no recorded interaction", err)
+	}
+}
+
+func TestRecordTransportRedaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassettes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The description makes the body longer than the HTTP trace keeps.
+	description := strings.Repeat("d", maxDebugBody)
+	respBody := `{"application_credential": {"id": "appcred", "secret": "s3cr3t", "description": "` + description + `"}}`
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Content-Type", "application/json")
+		return &http.Response{StatusCode: 201, Header: header, Body: ioutil.NopCloser(strings.NewReader(respBody))}, nil
+	})
+	transport, err := newRecordTransport(next, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "http://cloud/identity/v3/users/admin/application_credentials", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The caller gets the response as is.
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != respBody {
+		t.Errorf("RoundTrip() body = %.100s..., want the original body", body)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "00001-post.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var i interaction
+	if err := json.Unmarshal(data, &i); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"application_credential":{"description":"` + description + `","id":"appcred","secret":"<redacted>"}}`
+	if i.Body != want {
+		t.Errorf("recorded body = %.100s..., want %.100s...", i.Body, want)
+	}
+}
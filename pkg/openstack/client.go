@@ -15,87 +15,251 @@
 package openstack
 
 import (
+	"context"
 	"fmt"
+	"sync"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
 	log "github.com/sirupsen/logrus"
 )
 
-// OpenStack is an implementation of cloud provider Interface for OpenStack.
+// OpenStack is an implementation of cloud provider Interface for OpenStack. The service clients are created on
+// first use, so only the services a command calls need to be in the catalog.
 type OpenStack struct {
-	keystone *gophercloud.ServiceClient
-	Octavia  *gophercloud.ServiceClient
-	Nova     *gophercloud.ServiceClient
-	Neutron  *gophercloud.ServiceClient
-	Glance   *gophercloud.ServiceClient
+	provider *gophercloud.ProviderClient
 	config   OpenStackConfig
+
+	mu       sync.Mutex
+	clients  map[string]*gophercloud.ServiceClient
+	versions map[string]apiVersion
+	// registry is shared by the clients of all the regions as they use the same transport.
+	registry *endpointRegistry
+}
+
+// serviceTypes maps the service names used in the endpoint overrides to the service types in the catalog.
+var serviceTypes = map[string]string{
+	"keystone": "identity",
+	"octavia":  "load-balancer",
+	"nova":     "compute",
+	"neutron":  "network",
+	"glance":   "image",
 }
 
-// NewOpenStack gets openstack struct
-func NewOpenStack(cfg OpenStackConfig) (*OpenStack, error) {
+// overrideEndpoints makes the provider client return the configured endpoints instead of the ones in the catalog.
+func overrideEndpoints(provider *gophercloud.ProviderClient, overrides map[string]string) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	byType := make(map[string]string)
+	for name, url := range overrides {
+		serviceType, ok := serviceTypes[name]
+		if !ok {
+			return fmt.Errorf("unknown service %s in endpoint overrides", name)
+		}
+		byType[serviceType] = gophercloud.NormalizeURL(url)
+		log.WithFields(log.Fields{"service": name, "endpoint": url}).Debug("Using endpoint override")
+	}
+
+	locator := provider.EndpointLocator
+	provider.EndpointLocator = func(eo gophercloud.EndpointOpts) (string, error) {
+		if url, ok := byType[eo.Type]; ok {
+			return url, nil
+		}
+		return locator(eo)
+	}
+
+	return nil
+}
+
+// NewOpenStack gets openstack struct, ctx is only used for the authentication.
+func NewOpenStack(ctx context.Context, cfg OpenStackConfig) (*OpenStack, error) {
 	provider, err := openstack.NewClient(cfg.AuthURL)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = openstack.Authenticate(provider, cfg.ToAuthOptions()); err != nil {
-		return nil, err
+	switch gophercloud.Availability(cfg.Interface) {
+	case "", gophercloud.AvailabilityPublic, gophercloud.AvailabilityInternal, gophercloud.AvailabilityAdmin:
+	default:
+		return nil, fmt.Errorf("invalid endpoint interface %s, expected public, internal or admin", cfg.Interface)
 	}
 
-	// get keystone admin client
-	var keystone *gophercloud.ServiceClient
-	keystone, err = openstack.NewIdentityV3(provider, gophercloud.EndpointOpts{})
+	registry := newEndpointRegistry()
+	registry.register(provider.IdentityEndpoint, "keystone")
+	provider.HTTPClient, err = newHTTPClient(cfg, registry)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get keystone client: %v", err)
+		return nil, err
 	}
 
-	// get octavia service client
-	var lb *gophercloud.ServiceClient
-	lb, err = openstack.NewLoadBalancerV2(provider, gophercloud.EndpointOpts{
-		Region: cfg.Region,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to find octavia endpoint for region %s: %v", cfg.Region, err)
+	if cfg.UseApplicationCredential() {
+		if cfg.ApplicationCredentialSecret == "" {
+			return nil, fmt.Errorf("application credential secret is required")
+		}
+		if cfg.ApplicationCredentialID == "" && cfg.Username == "" {
+			return nil, fmt.Errorf("user name is required to authenticate with application credential name")
+		}
+		log.WithFields(log.Fields{"method": "application_credential"}).Debug("Authenticating")
+	} else {
+		log.WithFields(log.Fields{"method": "password"}).Debug("Authenticating")
 	}
 
-	// get neutron service client
-	var network *gophercloud.ServiceClient
-	network, err = openstack.NewNetworkV2(provider, gophercloud.EndpointOpts{
-		Region: cfg.Region,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to find neutron endpoint for region %s: %v", cfg.Region, err)
+	provider.Context = withRequestIDRecorder(ctx)
+	if cfg.TokenCache {
+		err = authenticateWithCache(provider, cfg)
+	} else if err = cfg.resolvePassword(); err == nil {
+		err = authenticateV3(provider, cfg.ToAuthOptions())
 	}
-
-	// get nova service client
-	var compute *gophercloud.ServiceClient
-	compute, err = openstack.NewComputeV2(provider, gophercloud.EndpointOpts{
-		Region: cfg.Region,
-	})
+	err = classify(err)
+	if id := lastRequestID(provider.Context); err != nil && id != "" {
+		err = &RequestError{RequestID: id, Err: err}
+	}
+	// The provider client outlives ctx, the requests get their context from withContext.
+	provider.Context = nil
 	if err != nil {
-		return nil, fmt.Errorf("failed to find compute v2 endpoint for region %s: %v", cfg.Region, err)
+		return nil, err
 	}
 
-	// get glance service client
-	var glance *gophercloud.ServiceClient
-	glance, err = openstack.NewImageServiceV2(provider, gophercloud.EndpointOpts{
-		Region: cfg.Region,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to find glance service endpoint for region %s: %v", cfg.Region, err)
+	if err := overrideEndpoints(provider, cfg.EndpointOverrides); err != nil {
+		return nil, err
 	}
 
 	os := OpenStack{
-		keystone: keystone,
-		Octavia:  lb,
-		Nova:     compute,
-		Neutron:  network,
-		Glance:   glance,
+		provider: provider,
 		config:   cfg,
+		clients:  make(map[string]*gophercloud.ServiceClient),
+		versions: make(map[string]apiVersion),
+		registry: registry,
 	}
 
 	log.Debug("openstack client initialized")
 
 	return &os, nil
 }
+
+// Cloud returns the cloud name in clouds.yaml the client is created for.
+func (os *OpenStack) Cloud() string {
+	return os.config.Cloud
+}
+
+// Region returns the region of the service clients.
+func (os *OpenStack) Region() string {
+	return os.config.Region
+}
+
+// ForRegion returns a client for the given region which shares the token with os.
+func (os *OpenStack) ForRegion(region string) *OpenStack {
+	cfg := os.config
+	cfg.Region = region
+
+	return &OpenStack{
+		provider: os.provider,
+		config:   cfg,
+		clients:  make(map[string]*gophercloud.ServiceClient),
+		versions: make(map[string]apiVersion),
+		registry: os.registry,
+	}
+}
+
+// logger returns the log entry with the region field if the region is set.
+func (os *OpenStack) logger() *log.Entry {
+	if os.config.Region == "" {
+		return log.NewEntry(log.StandardLogger())
+	}
+	return log.WithFields(log.Fields{"region": os.config.Region})
+}
+
+// withContext returns a copy of the service client whose requests are bound to ctx. The copy gets a provider client of
+// its own so the shared one is never modified, a reauthentication is done by the shared provider client and the new
+// token is copied back. The copy records the request IDs for requestError.
+func (os *OpenStack) withContext(ctx context.Context, client *gophercloud.ServiceClient) *gophercloud.ServiceClient {
+	provider := &gophercloud.ProviderClient{
+		IdentityBase:     os.provider.IdentityBase,
+		IdentityEndpoint: os.provider.IdentityEndpoint,
+		EndpointLocator:  os.provider.EndpointLocator,
+		HTTPClient:       os.provider.HTTPClient,
+		UserAgent:        os.provider.UserAgent,
+		Context:          withRequestIDRecorder(ctx),
+	}
+	provider.UseTokenLock()
+	provider.CopyTokenFrom(os.provider)
+	if os.provider.ReauthFunc != nil {
+		provider.ReauthFunc = func() error {
+			if err := os.provider.Reauthenticate(provider.Token()); err != nil {
+				return err
+			}
+			provider.CopyTokenFrom(os.provider)
+			return nil
+		}
+	}
+
+	sc := *client
+	sc.ProviderClient = provider
+	return &sc
+}
+
+// serviceClient returns the service client of the given service bound to ctx, the client is created on first use. If
+// negotiate is not nil, it's called once to pick the API version of the new client.
+func (os *OpenStack) serviceClient(ctx context.Context, name string, newClient func(*gophercloud.ProviderClient, gophercloud.EndpointOpts) (*gophercloud.ServiceClient, error), negotiate func(context.Context, *gophercloud.ServiceClient) (apiVersion, error)) (*gophercloud.ServiceClient, error) {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+
+	if client, ok := os.clients[name]; ok {
+		return os.withContext(ctx, client), nil
+	}
+
+	eo := gophercloud.EndpointOpts{
+		Region:       os.config.Region,
+		Availability: gophercloud.Availability(os.config.Interface),
+	}
+	// The auth url is used as keystone endpoint unless it's overridden.
+	if _, ok := os.config.EndpointOverrides["keystone"]; name == "keystone" && !ok {
+		eo = gophercloud.EndpointOpts{}
+	}
+
+	client, err := newClient(os.provider, eo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s endpoint for region %q: %v", name, os.config.Region, err)
+	}
+	os.registry.register(client.Endpoint, name)
+	if client.ResourceBase != "" {
+		os.registry.register(client.ResourceBase, name)
+	}
+	if negotiate != nil {
+		v, err := negotiate(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		os.versions[name] = v
+		os.logger().WithFields(log.Fields{"service": name, "version": v}).Debug("Negotiated API version")
+	}
+	os.clients[name] = client
+
+	return os.withContext(ctx, client), nil
+}
+
+// keystone returns the keystone service client bound to ctx.
+func (os *OpenStack) keystone(ctx context.Context) (*gophercloud.ServiceClient, error) {
+	return os.serviceClient(ctx, "keystone", openstack.NewIdentityV3, nil)
+}
+
+// Octavia returns the octavia service client bound to ctx.
+func (os *OpenStack) Octavia(ctx context.Context) (*gophercloud.ServiceClient, error) {
+	return os.serviceClient(ctx, "octavia", openstack.NewLoadBalancerV2, os.negotiateLoadBalancer)
+}
+
+// Nova returns the nova service client bound to ctx.
+func (os *OpenStack) Nova(ctx context.Context) (*gophercloud.ServiceClient, error) {
+	return os.serviceClient(ctx, "nova", openstack.NewComputeV2, os.negotiateCompute)
+}
+
+// Neutron returns the neutron service client bound to ctx.
+func (os *OpenStack) Neutron(ctx context.Context) (*gophercloud.ServiceClient, error) {
+	return os.serviceClient(ctx, "neutron", openstack.NewNetworkV2, nil)
+}
+
+// Glance returns the glance service client bound to ctx.
+func (os *OpenStack) Glance(ctx context.Context) (*gophercloud.ServiceClient, error) {
+	return os.serviceClient(ctx, "glance", openstack.NewImageServiceV2, nil)
+}
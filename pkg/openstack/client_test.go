@@ -0,0 +1,81 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lingxiankong/openstackcli-go/pkg/fakecloud"
+)
+
+// newFakeCloud serves a fake cloud in the initial state of the fixture, the default one if nil, and returns the
+// config of its admin user. The server has to be closed by the caller.
+func newFakeCloud(t *testing.T, f *fakecloud.Fixture) (*httptest.Server, OpenStackConfig) {
+	t.Helper()
+
+	if f == nil {
+		f = fakecloud.DefaultFixture()
+	}
+	server := httptest.NewServer(fakecloud.New(f))
+	cfg := OpenStackConfig{
+		AuthURL:          fakecloud.AuthURL(server.URL),
+		Username:         "admin",
+		Password:         "password",
+		ProjectName:      "admin",
+		Region:           "RegionOne",
+		RetryMaxAttempts: 1,
+	}
+	return server, cfg
+}
+
+func TestNewOpenStack(t *testing.T) {
+	server, cfg := newFakeCloud(t, nil)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *OpenStackConfig)
+		wantErr error
+	}{
+		{name: "password", modify: func(cfg *OpenStackConfig) {}},
+		{
+			name: "application credential",
+			modify: func(cfg *OpenStackConfig) {
+				cfg.ApplicationCredentialID = "appcred"
+				cfg.ApplicationCredentialSecret = "secret"
+			},
+		},
+		{name: "wrong password", modify: func(cfg *OpenStackConfig) { cfg.Password = "wrong" }, wantErr: ErrAuth},
+		{name: "unknown project", modify: func(cfg *OpenStackConfig) { cfg.ProjectName = "demo" }, wantErr: ErrAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			tt.modify(&cfg)
+
+			_, err := NewOpenStack(context.Background(), cfg)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("NewOpenStack() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewOpenStack() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
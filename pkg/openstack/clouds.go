@@ -0,0 +1,256 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	homedir "github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// Cloud is a single entry of the clouds.yaml file.
+type Cloud struct {
+	Auth       CloudAuth `yaml:"auth"`
+	RegionName string    `yaml:"region_name"`
+	CACert     string    `yaml:"cacert"`
+	ClientCert string    `yaml:"cert"`
+	ClientKey  string    `yaml:"key"`
+	Verify     *bool     `yaml:"verify"`
+	Interface  string    `yaml:"interface"`
+
+	ComputeAPIVersion      string `yaml:"compute_api_version"`
+	LoadBalancerAPIVersion string `yaml:"load_balancer_api_version"`
+
+	IdentityEndpointOverride     string `yaml:"identity_endpoint_override"`
+	LoadBalancerEndpointOverride string `yaml:"load_balancer_endpoint_override"`
+	ComputeEndpointOverride      string `yaml:"compute_endpoint_override"`
+	NetworkEndpointOverride      string `yaml:"network_endpoint_override"`
+	ImageEndpointOverride        string `yaml:"image_endpoint_override"`
+}
+
+// CloudAuth is the auth section of a clouds.yaml entry.
+type CloudAuth struct {
+	AuthURL     string `yaml:"auth_url"`
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+	ProjectName string `yaml:"project_name"`
+	ProjectID   string `yaml:"project_id"`
+
+	UserDomainName    string `yaml:"user_domain_name"`
+	UserDomainID      string `yaml:"user_domain_id"`
+	ProjectDomainName string `yaml:"project_domain_name"`
+	ProjectDomainID   string `yaml:"project_domain_id"`
+	DomainName        string `yaml:"domain_name"`
+	DomainID          string `yaml:"domain_id"`
+	SystemScope       string `yaml:"system_scope"`
+
+	ApplicationCredentialID     string `yaml:"application_credential_id"`
+	ApplicationCredentialName   string `yaml:"application_credential_name"`
+	ApplicationCredentialSecret string `yaml:"application_credential_secret"`
+}
+
+type cloudsFile struct {
+	Clouds map[string]Cloud `yaml:"clouds"`
+}
+
+// cloudsSearchPath returns the directories searched for clouds.yaml and secure.yaml, in the same order as the
+// python openstacksdk.
+func cloudsSearchPath() []string {
+	paths := []string{"."}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := homedir.Dir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		paths = append(paths, filepath.Join(configHome, "openstack"))
+	}
+
+	return append(paths, "/etc/openstack")
+}
+
+// findCloudsFile returns the first existing file with one of the given names, envVar overrides the search.
+func findCloudsFile(envVar string, names ...string) string {
+	if f := os.Getenv(envVar); f != "" {
+		return f
+	}
+
+	for _, dir := range cloudsSearchPath() {
+		for _, name := range names {
+			f := filepath.Join(dir, name)
+			if _, err := os.Stat(f); err == nil {
+				return f
+			}
+		}
+	}
+
+	return ""
+}
+
+func readYAMLFile(file string) (map[interface{}]interface{}, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	content := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+
+	return content, nil
+}
+
+// mergeYAML merges src into dst recursively, values in src take precedence.
+func mergeYAML(dst, src map[interface{}]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[interface{}]interface{})
+		dstMap, dstIsMap := dst[k].(map[interface{}]interface{})
+		if srcIsMap && dstIsMap {
+			mergeYAML(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// LoadClouds reads clouds.yaml and merges secure.yaml into it.
+func LoadClouds() (map[string]Cloud, error) {
+	cloudsPath := findCloudsFile("OS_CLIENT_CONFIG_FILE", "clouds.yaml", "clouds.yml")
+	if cloudsPath == "" {
+		return nil, fmt.Errorf("cannot find clouds.yaml in %v", cloudsSearchPath())
+	}
+
+	content, err := readYAMLFile(cloudsPath)
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{"file": cloudsPath}).Debug("Using clouds file")
+
+	if securePath := findCloudsFile("OS_CLIENT_SECURE_FILE", "secure.yaml", "secure.yml"); securePath != "" {
+		secure, err := readYAMLFile(securePath)
+		if err != nil {
+			return nil, err
+		}
+		mergeYAML(content, secure)
+		log.WithFields(log.Fields{"file": securePath}).Debug("Using secure file")
+	}
+
+	// Round trip the merged content to get the typed structure.
+	data, err := yaml.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	var f cloudsFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", cloudsPath, err)
+	}
+
+	return f.Clouds, nil
+}
+
+// MergeCloud fills the unset fields of the config from the clouds.yaml entry named by cfg.Cloud. Values already set
+// by flags or environment variables are kept.
+func (cfg *OpenStackConfig) MergeCloud() error {
+	if cfg.Cloud == "" {
+		return nil
+	}
+
+	clouds, err := LoadClouds()
+	if err != nil {
+		return err
+	}
+
+	cloud, ok := clouds[cfg.Cloud]
+	if !ok {
+		return fmt.Errorf("cloud %s not found in clouds.yaml", cfg.Cloud)
+	}
+
+	setDefault(&cfg.AuthURL, cloud.Auth.AuthURL)
+	setDefault(&cfg.Username, cloud.Auth.Username)
+	setDefault(&cfg.Password, cloud.Auth.Password)
+	setDefault(&cfg.ProjectName, cloud.Auth.ProjectName)
+	setDefault(&cfg.ProjectID, cloud.Auth.ProjectID)
+	setDefault(&cfg.Region, cloud.RegionName)
+	setDefault(&cfg.UserDomainName, cloud.Auth.UserDomainName)
+	setDefault(&cfg.UserDomainID, cloud.Auth.UserDomainID)
+	setDefault(&cfg.ProjectDomainName, cloud.Auth.ProjectDomainName)
+	setDefault(&cfg.ProjectDomainID, cloud.Auth.ProjectDomainID)
+	setDefault(&cfg.DomainName, cloud.Auth.DomainName)
+	setDefault(&cfg.DomainID, cloud.Auth.DomainID)
+	setDefault(&cfg.SystemScope, cloud.Auth.SystemScope)
+	setDefault(&cfg.CACert, cloud.CACert)
+	setDefault(&cfg.ClientCert, cloud.ClientCert)
+	setDefault(&cfg.ClientKey, cloud.ClientKey)
+	if cloud.Verify != nil && !*cloud.Verify {
+		cfg.Insecure = true
+	}
+	setDefault(&cfg.Interface, cloud.Interface)
+	setDefault(&cfg.ComputeAPIVersion, cloud.ComputeAPIVersion)
+	setDefault(&cfg.LoadBalancerAPIVersion, cloud.LoadBalancerAPIVersion)
+
+	cfg.SetEndpointOverrides(map[string]string{
+		"keystone": cloud.IdentityEndpointOverride,
+		"octavia":  cloud.LoadBalancerEndpointOverride,
+		"nova":     cloud.ComputeEndpointOverride,
+		"neutron":  cloud.NetworkEndpointOverride,
+		"glance":   cloud.ImageEndpointOverride,
+	})
+	setDefault(&cfg.ApplicationCredentialID, cloud.Auth.ApplicationCredentialID)
+	setDefault(&cfg.ApplicationCredentialName, cloud.Auth.ApplicationCredentialName)
+	setDefault(&cfg.ApplicationCredentialSecret, cloud.Auth.ApplicationCredentialSecret)
+
+	return nil
+}
+
+// Clone returns a copy of the config which can be modified without affecting cfg.
+func (cfg OpenStackConfig) Clone() OpenStackConfig {
+	if cfg.EndpointOverrides != nil {
+		overrides := make(map[string]string, len(cfg.EndpointOverrides))
+		for name, url := range cfg.EndpointOverrides {
+			overrides[name] = url
+		}
+		cfg.EndpointOverrides = overrides
+	}
+	return cfg
+}
+
+// SetEndpointOverrides adds the endpoint overrides of the services that don't have one yet.
+func (cfg *OpenStackConfig) SetEndpointOverrides(overrides map[string]string) {
+	for name, url := range overrides {
+		if url == "" {
+			continue
+		}
+		if cfg.EndpointOverrides == nil {
+			cfg.EndpointOverrides = make(map[string]string)
+		}
+		if _, ok := cfg.EndpointOverrides[name]; !ok {
+			cfg.EndpointOverrides[name] = url
+		}
+	}
+}
+
+func setDefault(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
@@ -18,23 +18,152 @@ import (
 	"github.com/gophercloud/gophercloud"
 )
 
+// defaultDomain is the keystone domain used when neither the user domain nor the project domain is configured.
+const defaultDomain = "default"
+
 // OpenStackConfig defines OpenStack credentials configuration.
 type OpenStackConfig struct {
-	Username    string
-	Password    string
-	ProjectName string `mapstructure:"project_name"`
-	AuthURL     string `mapstructure:"auth_url"`
-	Region      string
+	Cloud    string
+	Username string
+	Password string
+	// PasswordCommand is run by the shell to get the password if it's not configured.
+	PasswordCommand string
+	ProjectName     string
+	ProjectID       string
+	AuthURL         string
+	Region          string
+
+	UserDomainName    string
+	UserDomainID      string
+	ProjectDomainName string
+	ProjectDomainID   string
+
+	// DomainName and DomainID request a domain scoped token, SystemScope requests a system scoped token. They are
+	// ignored if a project is configured.
+	DomainName  string
+	DomainID    string
+	SystemScope string
+
+	ApplicationCredentialID     string
+	ApplicationCredentialName   string
+	ApplicationCredentialSecret string
+
+	// Interface is the endpoint interface in the catalog, public, internal or admin.
+	Interface string
+	// EndpointOverrides maps the service names(octavia, nova, neutron, glance, keystone) to the endpoints used
+	// instead of the catalog ones.
+	EndpointOverrides map[string]string
+
+	CACert     string
+	ClientCert string
+	ClientKey  string
+	Insecure   bool
+
+	// ComputeAPIVersion and LoadBalancerAPIVersion are the API versions requested for nova and octavia, the highest
+	// version supported by both osctl and the service is negotiated if not set.
+	ComputeAPIVersion      string
+	LoadBalancerAPIVersion string
+
+	// RetryMaxAttempts is the number of attempts of a request failed with a transient error, 1 disables retries.
+	// By default GET, HEAD and OPTIONS requests are retried on all the transient errors, PUT and DELETE requests only
+	// on 409, 429 and 503. RetryAllMethods retries POST requests as well, and all of them on all the transient errors.
+	RetryMaxAttempts int
+	RetryAllMethods  bool
+
+	// MaxQPS limits the requests per second to all the services, ServiceQPS limits the requests per second to each
+	// service(octavia, nova, neutron, glance or keystone). 0 means unlimited.
+	MaxQPS     float64
+	ServiceQPS map[string]float64
+
+	// DebugHTTP logs every request and response with the credentials redacted, DebugHTTPBodies adds the headers and
+	// bodies. The trace is written to DebugHTTPFile instead of the log if it's set.
+	DebugHTTP       bool
+	DebugHTTPBodies bool
+	DebugHTTPFile   string
+
+	// Record saves every HTTP interaction as a cassette file in the directory. Replay serves the responses recorded
+	// in the directory instead of sending the requests, ReplayMode is strict(default) or lenient.
+	Record     string
+	Replay     string
+	ReplayMode string
+
+	// Metrics counts the requests and records their latency, the metrics are written by WriteMetrics.
+	Metrics bool
+
+	// TokenCache enables reusing keystone tokens across osctl invocations.
+	TokenCache bool
+}
+
+// UseApplicationCredential returns true if the config should authenticate with a keystone application credential
+// rather than a password.
+func (cfg OpenStackConfig) UseApplicationCredential() bool {
+	return cfg.ApplicationCredentialID != "" || cfg.ApplicationCredentialName != ""
+}
+
+// setUserDomain sets the user domain in the auth options, falling back to the default domain.
+func (cfg OpenStackConfig) setUserDomain(opts *gophercloud.AuthOptions) {
+	switch {
+	case cfg.UserDomainID != "":
+		opts.DomainID = cfg.UserDomainID
+	case cfg.UserDomainName != "":
+		opts.DomainName = cfg.UserDomainName
+	default:
+		opts.DomainName = defaultDomain
+	}
 }
 
 // ToAuthOptions gets openstack auth options
-func (cfg OpenStackConfig) ToAuthOptions() gophercloud.AuthOptions {
-	return gophercloud.AuthOptions{
-		IdentityEndpoint: cfg.AuthURL,
-		Username:         cfg.Username,
-		Password:         cfg.Password,
-		TenantName:       cfg.ProjectName,
-		DomainName:       "default",
-		AllowReauth:      true,
+func (cfg OpenStackConfig) ToAuthOptions() *AuthOptions {
+	if cfg.UseApplicationCredential() {
+		opts := gophercloud.AuthOptions{
+			IdentityEndpoint:            cfg.AuthURL,
+			ApplicationCredentialID:     cfg.ApplicationCredentialID,
+			ApplicationCredentialSecret: cfg.ApplicationCredentialSecret,
+			AllowReauth:                 true,
+		}
+		// The user is only needed to look up the application credential by name. The token is always scoped to the
+		// project of the application credential, so no scope is set here.
+		if cfg.ApplicationCredentialID == "" {
+			opts.ApplicationCredentialName = cfg.ApplicationCredentialName
+			opts.Username = cfg.Username
+			cfg.setUserDomain(&opts)
+		}
+		return &AuthOptions{AuthOptions: opts}
 	}
+
+	opts := &AuthOptions{
+		AuthOptions: gophercloud.AuthOptions{
+			IdentityEndpoint: cfg.AuthURL,
+			Username:         cfg.Username,
+			Password:         cfg.Password,
+			AllowReauth:      true,
+		},
+	}
+	cfg.setUserDomain(&opts.AuthOptions)
+
+	switch {
+	case cfg.ProjectID != "":
+		opts.Scope.ProjectID = cfg.ProjectID
+	case cfg.ProjectName != "":
+		opts.Scope.ProjectName = cfg.ProjectName
+		// The project is looked up in the user domain if the project domain is not given.
+		switch {
+		case cfg.ProjectDomainID != "":
+			opts.Scope.ProjectDomainID = cfg.ProjectDomainID
+		case cfg.ProjectDomainName != "":
+			opts.Scope.ProjectDomainName = cfg.ProjectDomainName
+		case opts.DomainID != "":
+			opts.Scope.ProjectDomainID = opts.DomainID
+		default:
+			opts.Scope.ProjectDomainName = opts.DomainName
+		}
+	case cfg.DomainID != "":
+		opts.Scope.DomainID = cfg.DomainID
+	case cfg.DomainName != "":
+		opts.Scope.DomainName = cfg.DomainName
+	case cfg.SystemScope != "":
+		opts.Scope.System = true
+	}
+
+	return opts
 }
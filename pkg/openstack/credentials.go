@@ -0,0 +1,152 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	openRCLine = regexp.MustCompile(`^(?:export\s+)?(OS_[A-Za-z0-9_]+)=(.*)$`)
+	shellVar   = regexp.MustCompile(`\$(?:\{([A-Za-z0-9_]+)\}|([A-Za-z0-9_]+))`)
+)
+
+// ParseOpenRC reads the OS_* variables assigned in an openrc file. The file is parsed rather than run by a shell, a
+// variable that refers to anything but a previously assigned OS_* variable (e.g. the password read from the terminal)
+// is ignored.
+func ParseOpenRC(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := openRCLine.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+
+		value, ok := openRCValue(m[2], vars)
+		if !ok {
+			log.WithFields(log.Fields{"variable": m[1], "file": path}).Debug("Variable refers to unknown value, ignored")
+			continue
+		}
+		vars[m[1]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// openRCValue unquotes the value and expands the variables in it, returns false if a variable can't be expanded.
+func openRCValue(raw string, vars map[string]string) (string, bool) {
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		return raw[1 : len(raw)-1], true
+	}
+
+	if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' {
+		raw = strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\$`, "\x00").Replace(raw[1 : len(raw)-1])
+	} else if i := strings.Index(raw, " #"); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+
+	ok := true
+	value := shellVar.ReplaceAllStringFunc(raw, func(ref string) string {
+		m := shellVar.FindStringSubmatch(ref)
+		name := m[1] + m[2]
+		v, found := vars[name]
+		if !found {
+			ok = false
+		}
+		return v
+	})
+
+	return strings.Replace(value, "\x00", "$", -1), ok
+}
+
+// MergeEnv fills the unset fields of the config from OS_* variables, e.g. the ones parsed from an openrc file.
+func (cfg *OpenStackConfig) MergeEnv(vars map[string]string) {
+	fields := map[string]*string{
+		"OS_CLOUD":                         &cfg.Cloud,
+		"OS_AUTH_URL":                      &cfg.AuthURL,
+		"OS_USERNAME":                      &cfg.Username,
+		"OS_PASSWORD":                      &cfg.Password,
+		"OS_PROJECT_NAME":                  &cfg.ProjectName,
+		"OS_TENANT_NAME":                   &cfg.ProjectName,
+		"OS_PROJECT_ID":                    &cfg.ProjectID,
+		"OS_TENANT_ID":                     &cfg.ProjectID,
+		"OS_REGION_NAME":                   &cfg.Region,
+		"OS_USER_DOMAIN_NAME":              &cfg.UserDomainName,
+		"OS_USER_DOMAIN_ID":                &cfg.UserDomainID,
+		"OS_PROJECT_DOMAIN_NAME":           &cfg.ProjectDomainName,
+		"OS_PROJECT_DOMAIN_ID":             &cfg.ProjectDomainID,
+		"OS_DOMAIN_NAME":                   &cfg.DomainName,
+		"OS_DOMAIN_ID":                     &cfg.DomainID,
+		"OS_SYSTEM_SCOPE":                  &cfg.SystemScope,
+		"OS_APPLICATION_CREDENTIAL_ID":     &cfg.ApplicationCredentialID,
+		"OS_APPLICATION_CREDENTIAL_NAME":   &cfg.ApplicationCredentialName,
+		"OS_APPLICATION_CREDENTIAL_SECRET": &cfg.ApplicationCredentialSecret,
+		"OS_INTERFACE":                     &cfg.Interface,
+		"OS_CACERT":                        &cfg.CACert,
+		"OS_CERT":                          &cfg.ClientCert,
+		"OS_KEY":                           &cfg.ClientKey,
+		"OS_COMPUTE_API_VERSION":           &cfg.ComputeAPIVersion,
+		"OS_LOAD_BALANCER_API_VERSION":     &cfg.LoadBalancerAPIVersion,
+	}
+
+	for name, field := range fields {
+		if v, ok := vars[name]; ok {
+			setDefault(field, v)
+		}
+	}
+	if vars["OS_INSECURE"] == "true" {
+		cfg.Insecure = true
+	}
+}
+
+// resolvePassword runs the password command if the password is needed but not configured.
+func (cfg *OpenStackConfig) resolvePassword() error {
+	if cfg.Password != "" || cfg.PasswordCommand == "" || cfg.UseApplicationCredential() {
+		return nil
+	}
+
+	cmd := exec.Command("sh", "-c", cfg.PasswordCommand)
+	// The command may prompt the user, e.g. to unlock the password manager.
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to run password command: %v", err)
+	}
+
+	cfg.Password = strings.TrimRight(string(out), "\r\n")
+	if cfg.Password == "" {
+		return fmt.Errorf("password command returned empty password")
+	}
+
+	return nil
+}
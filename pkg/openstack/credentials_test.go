@@ -0,0 +1,91 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseOpenRC(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name:    "exported and plain assignments",
+			content: "export OS_AUTH_URL=https://keystone:5000/v3\nOS_REGION_NAME=RegionOne\n",
+			want:    map[string]string{"OS_AUTH_URL": "https://keystone:5000/v3", "OS_REGION_NAME": "RegionOne"},
+		},
+		{
+			name:    "single quotes keep the value as is",
+			content: "export OS_PASSWORD='pa$$ \"word\"'\n",
+			want:    map[string]string{"OS_PASSWORD": `pa$$ "word"`},
+		},
+		{
+			name:    "double quotes unescape the value",
+			content: `export OS_PASSWORD="pa\"ss\$word"` + "\n",
+			want:    map[string]string{"OS_PASSWORD": `pa"ss$word`},
+		},
+		{
+			name:    "previous variables are expanded",
+			content: "export OS_USERNAME=admin\nexport OS_PROJECT_NAME=$OS_USERNAME\nexport OS_TENANT_NAME=\"${OS_USERNAME}-project\"\n",
+			want:    map[string]string{"OS_USERNAME": "admin", "OS_PROJECT_NAME": "admin", "OS_TENANT_NAME": "admin-project"},
+		},
+		{
+			name:    "unknown variables are ignored",
+			content: "echo \"Please enter your password: \"\nread -sr OS_PASSWORD_INPUT\nexport OS_PASSWORD=$OS_PASSWORD_INPUT\n",
+			want:    map[string]string{},
+		},
+		{
+			name:    "comments and other lines are ignored",
+			content: "#!/usr/bin/env bash\n# OS_CLOUD=commented\nunset OS_TENANT_ID\n  export OS_INTERFACE=public # the public endpoints\n",
+			want:    map[string]string{"OS_INTERFACE": "public"},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "openrc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "openrc.sh")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ParseOpenRC(path)
+			if err != nil {
+				t.Fatalf("ParseOpenRC() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseOpenRC() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOpenRCMissingFile(t *testing.T) {
+	if _, err := ParseOpenRC(filepath.Join(os.TempDir(), "osctl-no-such-openrc")); err == nil {
+		t.Error("ParseOpenRC() error = nil, want an error for a missing file")
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxDebugBody is the maximum size of a body written to the HTTP trace.
+const maxDebugBody = 64 * 1024
+
+const redacted = "<redacted>"
+
+// sensitiveHeaders are the headers whose values are never written to the HTTP trace.
+var sensitiveHeaders = []string{"X-Auth-Token", "X-Subject-Token", "Authorization", "Cookie", "Set-Cookie"}
+
+var (
+	traceLoggersMu sync.Mutex
+	// traceLoggers are the loggers of the HTTP trace files, shared by all the clients writing to the same file.
+	traceLoggers = make(map[string]*log.Logger)
+)
+
+// traceLogger returns the logger of the HTTP trace, the standard logger unless a trace file is configured.
+func traceLogger(file string) (*log.Logger, error) {
+	if file == "" {
+		return log.StandardLogger(), nil
+	}
+
+	traceLoggersMu.Lock()
+	defer traceLoggersMu.Unlock()
+
+	if logger, ok := traceLoggers[file]; ok {
+		return logger, nil
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open HTTP trace file: %v", err)
+	}
+	logger := log.New()
+	logger.Out = f
+	logger.Formatter = &log.TextFormatter{FullTimestamp: true, DisableColors: true}
+	traceLoggers[file] = logger
+
+	return logger, nil
+}
+
+// debugTransport logs the requests and responses with the credentials redacted.
+type debugTransport struct {
+	next     http.RoundTripper
+	logger   *log.Logger
+	registry *endpointRegistry
+	bodies   bool
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields := log.Fields{"method": req.Method, "url": req.URL.String()}
+	if service := t.registry.lookup(req.URL.String()); service != "" {
+		fields["service"] = service
+	}
+
+	if t.bodies {
+		fields["headers"] = redactHeaders(req.Header)
+		if req.Body != nil && req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				data, _ := ioutil.ReadAll(body)
+				body.Close()
+				fields["body"] = redactBody(data, req.Header.Get("Content-Type"))
+			}
+		}
+	}
+	t.logger.WithFields(fields).Info("HTTP request")
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	fields = log.Fields{"method": req.Method, "url": req.URL.String(), "duration": time.Since(start).Round(time.Millisecond)}
+	if err != nil {
+		fields["error"] = err
+		t.logger.WithFields(fields).Info("HTTP response")
+		return resp, err
+	}
+
+	fields["status"] = resp.StatusCode
+	fields["request_id"] = resp.Header.Get("X-Openstack-Request-Id")
+	if t.bodies {
+		fields["headers"] = redactHeaders(resp.Header)
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+		fields["body"] = redactBody(data, resp.Header.Get("Content-Type"))
+	}
+	t.logger.WithFields(fields).Info("HTTP response")
+
+	return resp, nil
+}
+
+// redactHeaders returns the headers in a single line with the values of the sensitive headers replaced.
+func redactHeaders(header http.Header) string {
+	h := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if h.Get(name) != "" {
+			h.Set(name, redacted)
+		}
+	}
+
+	var parts []string
+	for name, values := range h {
+		parts = append(parts, fmt.Sprintf("%s: %s", name, strings.Join(values, ",")))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
+
+// redactBody returns the JSON body with the values of the password and secret fields replaced, truncated for the HTTP
+// trace. Other bodies are only described by their size and type as they can't be redacted.
+func redactBody(data []byte, contentType string) string {
+	out := redactFullBody(data, contentType)
+	if len(out) > maxDebugBody {
+		return out[:maxDebugBody] + "...(truncated)"
+	}
+	return out
+}
+
+// redactFullBody is redactBody without the truncation.
+func redactFullBody(data []byte, contentType string) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Sprintf("<%d bytes of %s>", len(data), contentType)
+	}
+	redactJSON(v)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprintf("<%d bytes of %s>", len(data), contentType)
+	}
+	return string(bytes.TrimSpace(buf.Bytes()))
+}
+
+// redactJSON replaces the values of the sensitive fields in the decoded JSON in place.
+func redactJSON(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			// Only the values are redacted, e.g. the user of the password auth method is kept.
+			if _, ok := value.(string); ok && sensitiveKey(key) {
+				v[key] = redacted
+				continue
+			}
+			// The token ID in the body of the token auth method.
+			if token, ok := value.(map[string]interface{}); ok && key == "token" {
+				if _, ok := token["id"]; ok {
+					token["id"] = redacted
+				}
+			}
+			redactJSON(value)
+		}
+	case []interface{}:
+		for _, value := range v {
+			redactJSON(value)
+		}
+	}
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	return strings.Contains(key, "password") || strings.Contains(key, "secret") || key == "adminpass"
+}
@@ -0,0 +1,95 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+		want        string
+	}{
+		{
+			name: "empty body",
+			want: "",
+		},
+		{
+			name: "password auth",
+			data: `{"auth":{"identity":{"methods":["password"],"password":{"user":{"name":"admin","password":"secret"}}}}}`,
+			want: `{"auth":{"identity":{"methods":["password"],"password":{"user":{"name":"admin","password":"<redacted>"}}}}}`,
+		},
+		{
+			name: "token auth",
+			data: `{"auth":{"identity":{"methods":["token"],"token":{"id":"gAAAAA"}}}}`,
+			want: `{"auth":{"identity":{"methods":["token"],"token":{"id":"<redacted>"}}}}`,
+		},
+		{
+			name: "application credential secret in a list",
+			data: `{"credentials":[{"id":"appcred","secret":"s3cret"},{"adminPass":"x"}]}`,
+			want: `{"credentials":[{"id":"appcred","secret":"<redacted>"},{"adminPass":"<redacted>"}]}`,
+		},
+		{
+			name: "non-string sensitive values are kept",
+			data: `{"password_expires_at":null,"secrets":{"count":1}}`,
+			want: `{"password_expires_at":null,"secrets":{"count":1}}`,
+		},
+		{
+			name:        "not JSON",
+			data:        "<html>oops</html>",
+			contentType: "text/html",
+			want:        "<17 bytes of text/html>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactBody([]byte(tt.data), tt.contentType); got != tt.want {
+				t.Errorf("redactBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactBodyTruncated(t *testing.T) {
+	data, _ := json.Marshal(map[string]string{"description": strings.Repeat("a", maxDebugBody)})
+	got := redactBody(data, "application/json")
+	if !strings.HasSuffix(got, "...(truncated)") || len(got) != maxDebugBody+len("...(truncated)") {
+		t.Errorf("redactBody() returned %d bytes, want the body truncated to %d bytes", len(got), maxDebugBody)
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(`[{"user":{"name":"admin","Password":"x"}},"password",{"application_credential_secret":"y"}]`), &v); err != nil {
+		t.Fatal(err)
+	}
+	redactJSON(v)
+
+	want := []interface{}{
+		map[string]interface{}{"user": map[string]interface{}{"name": "admin", "Password": redacted}},
+		"password",
+		map[string]interface{}{"application_credential_secret": redacted},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("redactJSON() = %v, want %v", v, want)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+)
+
+// The classes of the errors returned by the client, check them with errors.Is.
+var (
+	// ErrAuth is the class of the authentication and authorization failures.
+	ErrAuth = errors.New("authentication failed")
+	// ErrNotFound is the class of the errors of missing resources.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is the class of the errors of resources in a state not allowing the operation, e.g. immutable
+	// load balancers.
+	ErrConflict = errors.New("conflict")
+	// ErrTimeout is the class of the operations that didn't finish in time.
+	ErrTimeout = errors.New("timed out")
+	// ErrQuota is the class of the errors of exceeded quotas.
+	ErrQuota = errors.New("quota exceeded")
+	// ErrPartial is the class of the operations over several targets that failed for some of them only.
+	ErrPartial = errors.New("partially failed")
+)
+
+// classError puts an error into one of the error classes.
+type classError struct {
+	class error
+	err   error
+}
+
+func (e *classError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classError) Unwrap() error {
+	return e.err
+}
+
+func (e *classError) Is(target error) bool {
+	return target == e.class
+}
+
+// responseCode returns the status code and body of the unexpected response err is about.
+func responseCode(err error) (int, []byte, bool) {
+	switch e := err.(type) {
+	case gophercloud.ErrUnexpectedResponseCode:
+		return e.Actual, e.Body, true
+	case *gophercloud.ErrUnexpectedResponseCode:
+		return e.Actual, e.Body, true
+	case gophercloud.ErrDefault400:
+		return e.Actual, e.Body, true
+	case gophercloud.ErrDefault401:
+		return e.Actual, e.Body, true
+	case gophercloud.ErrDefault403:
+		return e.Actual, e.Body, true
+	case gophercloud.ErrDefault404:
+		return e.Actual, e.Body, true
+	case gophercloud.ErrDefault405:
+		return e.Actual, e.Body, true
+	case gophercloud.ErrDefault408:
+		return e.Actual, e.Body, true
+	case gophercloud.ErrDefault409:
+		return e.Actual, e.Body, true
+	case gophercloud.ErrDefault429:
+		return e.Actual, e.Body, true
+	case gophercloud.ErrDefault500:
+		return e.Actual, e.Body, true
+	case gophercloud.ErrDefault503:
+		return e.Actual, e.Body, true
+	case *gophercloud.ErrUnableToReauthenticate:
+		return http.StatusUnauthorized, nil, true
+	case *gophercloud.ErrErrorAfterReauthentication:
+		return responseCode(e.ErrOriginal)
+	}
+	return 0, nil, false
+}
+
+// classify puts the error of an API call into its class by the response status code.
+func classify(err error) error {
+	var ce *classError
+	if err == nil || errors.As(err, &ce) {
+		return err
+	}
+
+	code, body, ok := responseCode(err)
+	if !ok {
+		return err
+	}
+
+	var class error
+	switch {
+	case (code == http.StatusForbidden || code == http.StatusConflict || code == http.StatusRequestEntityTooLarge) &&
+		strings.Contains(strings.ToLower(string(body)), "quota"):
+		class = ErrQuota
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		class = ErrAuth
+	case code == http.StatusNotFound:
+		class = ErrNotFound
+	case code == http.StatusConflict:
+		class = ErrConflict
+	case code == http.StatusRequestTimeout || code == http.StatusGatewayTimeout:
+		class = ErrTimeout
+	default:
+		return err
+	}
+	return &classError{class: class, err: err}
+}
+
+// PartialError is returned when an operation over several targets, e.g. regions or load balancers, failed for some
+// of them only. It wraps the first error.
+type PartialError struct {
+	Total int
+	Errs  []error
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d of %d failed: %s", len(e.Errs), e.Total, strings.Join(msgs, "; "))
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Errs[0]
+}
+
+func (e *PartialError) Is(target error) bool {
+	return target == ErrPartial
+}
+
+// CombineErrors returns the error of an operation over total targets given the errors of the failed ones: nil if
+// none failed, a PartialError if some failed, or the first error if all failed.
+func CombineErrors(total int, errs []error) error {
+	switch {
+	case len(errs) == 0:
+		return nil
+	case len(errs) < total:
+		return &PartialError{Total: total, Errs: errs}
+	case len(errs) == 1:
+		return errs[0]
+	}
+	return fmt.Errorf("all %d failed, the first error: %w", total, errs[0])
+}
@@ -0,0 +1,133 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+)
+
+func responseError(code int, body string) gophercloud.ErrUnexpectedResponseCode {
+	return gophercloud.ErrUnexpectedResponseCode{Actual: code, Body: []byte(body)}
+}
+
+func TestClassify(t *testing.T) {
+	classes := []error{ErrAuth, ErrNotFound, ErrConflict, ErrTimeout, ErrQuota}
+
+	tests := []struct {
+		name string
+		err  error
+		// want is the class of the error, nil if it's returned as is.
+		want error
+	}{
+		{name: "unauthorized", err: gophercloud.ErrDefault401{ErrUnexpectedResponseCode: responseError(401, "")}, want: ErrAuth},
+		{name: "forbidden", err: gophercloud.ErrDefault403{ErrUnexpectedResponseCode: responseError(403, "Policy doesn't allow it")}, want: ErrAuth},
+		{name: "quota forbidden", err: gophercloud.ErrDefault403{ErrUnexpectedResponseCode: responseError(403, "Quota exceeded for instances")}, want: ErrQuota},
+		{name: "quota conflict", err: gophercloud.ErrDefault409{ErrUnexpectedResponseCode: responseError(409, "Quota has been met for resources: Load Balancer")}, want: ErrQuota},
+		{name: "quota too large", err: responseError(413, `{"overLimit": {"message": "Quota exceeded"}}`), want: ErrQuota},
+		{name: "too large", err: responseError(413, "Request body too large"), want: nil},
+		{name: "not found", err: gophercloud.ErrDefault404{ErrUnexpectedResponseCode: responseError(404, "")}, want: ErrNotFound},
+		{name: "pointer", err: &gophercloud.ErrUnexpectedResponseCode{Actual: 404}, want: ErrNotFound},
+		{name: "conflict", err: gophercloud.ErrDefault409{ErrUnexpectedResponseCode: responseError(409, "Invalid state PENDING_UPDATE")}, want: ErrConflict},
+		{name: "request timeout", err: gophercloud.ErrDefault408{ErrUnexpectedResponseCode: responseError(408, "")}, want: ErrTimeout},
+		{name: "gateway timeout", err: responseError(504, ""), want: ErrTimeout},
+		{name: "server error", err: gophercloud.ErrDefault500{ErrUnexpectedResponseCode: responseError(500, "")}, want: nil},
+		{name: "reauthentication", err: &gophercloud.ErrUnableToReauthenticate{ErrOriginal: errors.New("token expired")}, want: ErrAuth},
+		{
+			name: "after reauthentication",
+			err:  &gophercloud.ErrErrorAfterReauthentication{ErrOriginal: gophercloud.ErrDefault404{ErrUnexpectedResponseCode: responseError(404, "")}},
+			want: ErrNotFound,
+		},
+		{name: "other", err: errors.New("connection refused"), want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classify(tt.err)
+			for _, class := range classes {
+				if is := errors.Is(got, class); is != (class == tt.want) {
+					t.Errorf("errors.Is(classify(), %v) = %v", class, is)
+				}
+			}
+			if got.Error() != tt.err.Error() {
+				t.Errorf("classify().Error() = %s, want %s", got, tt.err)
+			}
+		})
+	}
+}
+
+func TestClassifyClassified(t *testing.T) {
+	if err := classify(nil); err != nil {
+		t.Errorf("classify(nil) = %v", err)
+	}
+
+	err := fmt.Errorf("failed to get the loadbalancer: %w", classify(responseError(404, "")))
+	if got := classify(err); got != err {
+		t.Errorf("classify() = %v, want the classified error as is", got)
+	}
+}
+
+func TestCombineErrors(t *testing.T) {
+	notFound := classify(responseError(404, ""))
+	conflict := classify(responseError(409, ""))
+
+	tests := []struct {
+		name        string
+		total       int
+		errs        []error
+		want        string
+		wantPartial bool
+		// wantIs is an error the result has to match with errors.Is.
+		wantIs error
+	}{
+		{name: "none failed", total: 3},
+		{name: "one of several failed", total: 3, errs: []error{notFound}, want: "1 of 3 failed: " + notFound.Error(), wantPartial: true, wantIs: ErrNotFound},
+		{
+			name:        "some failed",
+			total:       3,
+			errs:        []error{conflict, notFound},
+			want:        fmt.Sprintf("2 of 3 failed: %s; %s", conflict, notFound),
+			wantPartial: true,
+			wantIs:      ErrConflict,
+		},
+		{name: "the only one failed", total: 1, errs: []error{notFound}, want: notFound.Error(), wantIs: ErrNotFound},
+		{name: "all failed", total: 2, errs: []error{conflict, notFound}, want: "all 2 failed, the first error: " + conflict.Error(), wantIs: ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CombineErrors(tt.total, tt.errs)
+			if tt.errs == nil {
+				if err != nil {
+					t.Errorf("CombineErrors() = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("CombineErrors() = %v, want %s", err, tt.want)
+			}
+			if errors.Is(err, ErrPartial) != tt.wantPartial {
+				t.Errorf("errors.Is(CombineErrors(), ErrPartial) = %v, want %v", !tt.wantPartial, tt.wantPartial)
+			}
+			if !errors.Is(err, tt.wantIs) {
+				t.Errorf("errors.Is(CombineErrors(), %v) = false", tt.wantIs)
+			}
+		})
+	}
+}
@@ -15,22 +15,28 @@
 package openstack
 
 import (
+	"context"
 	"fmt"
 	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
 )
 
 // GetAmphoraImage gets latest amphora image ID.
-func (os *OpenStack) GetAmphoraImage() (string, error) {
+func (os *OpenStack) GetAmphoraImage(ctx context.Context) (string, error) {
 	listOpts := images.ListOpts{
 		Limit: 1,
 		Tags:  []string{"amphora"},
 		Sort:  "created_at:desc",
 	}
-	allPages, err := images.List(os.Glance, listOpts).AllPages()
+	client, err := os.Glance(ctx)
 	if err != nil {
 		return "", err
 	}
 
+	allPages, err := images.List(client, listOpts).AllPages()
+	if err != nil {
+		return "", requestError(client, err)
+	}
+
 	allImages, err := images.ExtractImages(allPages)
 	if err != nil {
 		return "", err
@@ -15,21 +15,33 @@
 package openstack
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack/identity/v3/projects"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
 )
 
 // GetProjects return all the projects information.
-func (os *OpenStack) GetProjects() ([]projects.Project, error) {
+func (os *OpenStack) GetProjects(ctx context.Context) ([]projects.Project, error) {
 	var iTrue bool = true
 	listOpts := projects.ListOpts{
 		Enabled: &iTrue,
 	}
 
-	allPages, err := projects.List(os.keystone, listOpts).AllPages()
+	client, err := os.keystone(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	allPages, err := projects.List(client, listOpts).AllPages()
+	if err != nil {
+		return nil, requestError(client, err)
+	}
+
 	allProjects, err := projects.ExtractProjects(allPages)
 	if err != nil {
 		return nil, err
@@ -37,3 +49,162 @@ func (os *OpenStack) GetProjects() ([]projects.Project, error) {
 
 	return allProjects, nil
 }
+
+// catalogResult is the auth result of the provider client that contains the service catalog.
+type catalogResult interface {
+	ExtractServiceCatalog() (*tokens.ServiceCatalog, error)
+	ExtractInto(v interface{}) error
+}
+
+// authResult returns the auth result of the token the provider client is authenticated with.
+func (os *OpenStack) authResult() (catalogResult, error) {
+	result, ok := os.provider.GetAuthResult().(catalogResult)
+	if !ok {
+		return nil, fmt.Errorf("service catalog is not available")
+	}
+	return result, nil
+}
+
+// availability returns the configured endpoint interface, public by default.
+func (os *OpenStack) availability() gophercloud.Availability {
+	if os.config.Interface == "" {
+		return gophercloud.AvailabilityPublic
+	}
+	return gophercloud.Availability(os.config.Interface)
+}
+
+// EndpointRegion returns the region of the catalog endpoint, the region ID or the deprecated region name of the older
+// Keystone releases.
+func EndpointRegion(endpoint tokens.Endpoint) string {
+	if endpoint.RegionID != "" {
+		return endpoint.RegionID
+	}
+	return endpoint.Region
+}
+
+// TokenInfo is the information of the token the client is authenticated with.
+type TokenInfo struct {
+	User      tokens.User     `json:"user"`
+	Project   *tokens.Project `json:"project,omitempty"`
+	Domain    *tokens.Domain  `json:"domain,omitempty"`
+	System    map[string]bool `json:"system,omitempty"`
+	Roles     []tokens.Role   `json:"roles"`
+	Methods   []string        `json:"methods"`
+	IssuedAt  time.Time       `json:"issued_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// TokenInfo returns the user, scope, roles and expiry of the token.
+func (os *OpenStack) TokenInfo() (*TokenInfo, error) {
+	result, err := os.authResult()
+	if err != nil {
+		return nil, err
+	}
+
+	var info TokenInfo
+	if err := result.ExtractInto(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Token returns the ID of the token the client is authenticated with.
+func (os *OpenStack) Token() string {
+	return os.provider.Token()
+}
+
+// Catalog returns the services in the catalog of the token with the endpoints in the region and interface of the
+// client. Services without such an endpoint are left out, all the regions are included if the region is not set.
+func (os *OpenStack) Catalog() ([]tokens.CatalogEntry, error) {
+	result, err := os.authResult()
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := result.ExtractServiceCatalog()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []tokens.CatalogEntry
+	for _, entry := range catalog.Entries {
+		var endpoints []tokens.Endpoint
+		for _, endpoint := range entry.Endpoints {
+			if gophercloud.Availability(endpoint.Interface) != os.availability() {
+				continue
+			}
+			if os.config.Region != "" && EndpointRegion(endpoint) != os.config.Region {
+				continue
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+		if len(endpoints) > 0 {
+			entry.Endpoints = endpoints
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Type < entries[j].Type })
+
+	return entries, nil
+}
+
+// Regions returns the regions of the endpoints in the service catalog of the token. If services(e.g. octavia or nova)
+// are given, only the regions with an endpoint of every one of them are returned. A service with an endpoint override
+// is available in all the regions.
+func (os *OpenStack) Regions(services ...string) ([]string, error) {
+	var required []string
+	for _, name := range services {
+		serviceType, ok := serviceTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown service %s", name)
+		}
+		if _, ok := os.config.EndpointOverrides[name]; !ok {
+			required = append(required, serviceType)
+		}
+	}
+
+	result, err := os.authResult()
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := result.ExtractServiceCatalog()
+	if err != nil {
+		return nil, err
+	}
+
+	// found maps the regions to the types of the services with an endpoint in them.
+	found := make(map[string]map[string]bool)
+	for _, entry := range catalog.Entries {
+		for _, endpoint := range entry.Endpoints {
+			if gophercloud.Availability(endpoint.Interface) != os.availability() {
+				continue
+			}
+			region := EndpointRegion(endpoint)
+			if region == "" {
+				continue
+			}
+			if found[region] == nil {
+				found[region] = make(map[string]bool)
+			}
+			found[region][entry.Type] = true
+		}
+	}
+
+	var regions []string
+	for region, types := range found {
+		if hasServices(types, required) {
+			regions = append(regions, region)
+		}
+	}
+	sort.Strings(regions)
+
+	return regions, nil
+}
+
+func hasServices(types map[string]bool, required []string) bool {
+	for _, serviceType := range required {
+		if !types[serviceType] {
+			return false
+		}
+	}
+	return true
+}
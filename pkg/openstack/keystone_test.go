@@ -0,0 +1,74 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/lingxiankong/openstackcli-go/pkg/fakecloud"
+)
+
+func TestRegions(t *testing.T) {
+	f := fakecloud.DefaultFixture()
+	f.Regions["RegionTwo"] = &fakecloud.Region{Services: []string{"compute", "network", "image"}}
+	server, cfg := newFakeCloud(t, f)
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		services  []string
+		overrides map[string]string
+		want      []string
+		wantErr   bool
+	}{
+		{name: "any service", want: []string{"RegionOne", "RegionTwo"}},
+		{name: "available everywhere", services: []string{"nova", "glance"}, want: []string{"RegionOne", "RegionTwo"}},
+		{name: "missing in a region", services: []string{"octavia", "nova"}, want: []string{"RegionOne"}},
+		{
+			name:      "endpoint override",
+			services:  []string{"octavia", "nova"},
+			overrides: map[string]string{"octavia": server.URL + "/RegionOne/load-balancer/"},
+			want:      []string{"RegionOne", "RegionTwo"},
+		},
+		{name: "unknown service", services: []string{"swift"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			cfg.EndpointOverrides = tt.overrides
+			client, err := NewOpenStack(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := client.Regions(tt.services...)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Regions() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Regions() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Regions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
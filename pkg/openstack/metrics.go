@@ -0,0 +1,230 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds in seconds of the request latency histogram buckets.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// idCollections are the collections whose next path segment is a resource ID.
+var idCollections = map[string]bool{
+	"amphorae":         true,
+	"healthmonitors":   true,
+	"images":           true,
+	"l7policies":       true,
+	"listeners":        true,
+	"loadbalancers":    true,
+	"members":          true,
+	"networks":         true,
+	"os-server-groups": true,
+	"pools":            true,
+	"ports":            true,
+	"projects":         true,
+	"rules":            true,
+	"security-groups":  true,
+	"servers":          true,
+	"subnets":          true,
+	"users":            true,
+}
+
+// idPattern matches the path segments which look like IDs, e.g. UUIDs or the hex IDs of keystone.
+var idPattern = regexp.MustCompile(`^([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{32})$`)
+
+// requestLabels are the labels of the API call metrics.
+type requestLabels struct {
+	service     string
+	method      string
+	url         string
+	statusClass string
+}
+
+type latencyHistogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// apiMetrics collects the API calls of all the clients in the process.
+var apiMetrics = struct {
+	mu         sync.Mutex
+	histograms map[requestLabels]*latencyHistogram
+}{histograms: make(map[requestLabels]*latencyHistogram)}
+
+func observeRequest(labels requestLabels, d time.Duration) {
+	apiMetrics.mu.Lock()
+	defer apiMetrics.mu.Unlock()
+
+	h, ok := apiMetrics.histograms[labels]
+	if !ok {
+		h = &latencyHistogram{buckets: make([]uint64, len(latencyBuckets))}
+		apiMetrics.histograms[labels] = h
+	}
+	secs := d.Seconds()
+	for i, le := range latencyBuckets {
+		if secs <= le {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += secs
+}
+
+// metricsTransport records the number and latency of the requests, labelled by service, method, URL template and
+// status class.
+type metricsTransport struct {
+	next     http.RoundTripper
+	registry *endpointRegistry
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	service, prefix := t.registry.match(req.URL.String())
+	if service == "" {
+		service = "unknown"
+	}
+	labels := requestLabels{
+		service:     service,
+		method:      req.Method,
+		url:         urlTemplate(req.URL, prefix),
+		statusClass: "error",
+	}
+	if err == nil {
+		labels.statusClass = fmt.Sprintf("%dxx", resp.StatusCode/100)
+	}
+	observeRequest(labels, time.Since(start))
+
+	return resp, err
+}
+
+// urlTemplate returns the path of the URL relative to the service endpoint, with the resource IDs replaced by {id}
+// to keep the number of series small.
+func urlTemplate(u *url.URL, endpoint string) string {
+	path := u.Path
+	if endpoint != "" {
+		if e, err := url.Parse(endpoint); err == nil {
+			path = strings.TrimPrefix(path, strings.TrimSuffix(e.Path, "/"))
+		}
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, s := range segments {
+		if idPattern.MatchString(s) || i > 0 && idCollections[segments[i-1]] {
+			segments[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// WriteMetrics writes the API call metrics in the Prometheus text exposition format.
+func WriteMetrics(w io.Writer) error {
+	apiMetrics.mu.Lock()
+	defer apiMetrics.mu.Unlock()
+
+	var series []requestLabels
+	for labels := range apiMetrics.histograms {
+		series = append(series, labels)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		a, b := series[i], series[j]
+		if a.service != b.service {
+			return a.service < b.service
+		}
+		if a.url != b.url {
+			return a.url < b.url
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.statusClass < b.statusClass
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# HELP osctl_api_requests_total Number of OpenStack API requests sent by osctl.")
+	fmt.Fprintln(bw, "# TYPE osctl_api_requests_total counter")
+	for _, labels := range series {
+		fmt.Fprintf(bw, "osctl_api_requests_total{%s} %d\n", labels, apiMetrics.histograms[labels].count)
+	}
+
+	fmt.Fprintln(bw, "# HELP osctl_api_request_duration_seconds Latency of the OpenStack API requests sent by osctl.")
+	fmt.Fprintln(bw, "# TYPE osctl_api_request_duration_seconds histogram")
+	for _, labels := range series {
+		h := apiMetrics.histograms[labels]
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(bw, "osctl_api_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, formatFloat(le), h.buckets[i])
+		}
+		fmt.Fprintf(bw, "osctl_api_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(bw, "osctl_api_request_duration_seconds_sum{%s} %s\n", labels, formatFloat(h.sum))
+		fmt.Fprintf(bw, "osctl_api_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+
+	return bw.Flush()
+}
+
+// WriteMetricsFile writes the API call metrics to the file. The file is replaced atomically so that a collector, e.g.
+// the textfile collector of node_exporter, never reads a partial file.
+func WriteMetricsFile(file string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".metrics-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := WriteMetrics(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+func (l requestLabels) String() string {
+	return fmt.Sprintf(`service="%s",method="%s",url="%s",status_class="%s"`,
+		escapeLabel(l.service), escapeLabel(l.method), escapeLabel(l.url), escapeLabel(l.statusClass))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
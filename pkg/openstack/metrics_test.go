@@ -0,0 +1,83 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestURLTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		endpoint string
+		want     string
+	}{
+		{
+			name:     "collection",
+			url:      "http://cloud/RegionOne/load-balancer/v2/lbaas/loadbalancers?project_id=demo",
+			endpoint: "http://cloud/RegionOne/load-balancer/",
+			want:     "/v2/lbaas/loadbalancers",
+		},
+		{
+			name:     "resource",
+			url:      "http://cloud/RegionOne/load-balancer/v2/lbaas/loadbalancers/lb-web",
+			endpoint: "http://cloud/RegionOne/load-balancer/",
+			want:     "/v2/lbaas/loadbalancers/{id}",
+		},
+		{
+			name:     "action",
+			url:      "http://cloud/RegionOne/load-balancer/v2/octavia/amphorae/amp-web-1/failover",
+			endpoint: "http://cloud/RegionOne/load-balancer",
+			want:     "/v2/octavia/amphorae/{id}/failover",
+		},
+		{
+			name:     "sub-resource",
+			url:      "http://cloud/RegionOne/load-balancer/v2/lbaas/pools/pool-web/members/member-web-1",
+			endpoint: "http://cloud/RegionOne/load-balancer/",
+			want:     "/v2/lbaas/pools/{id}/members/{id}",
+		},
+		{
+			name:     "UUID out of a known collection",
+			url:      "http://cloud/RegionOne/compute/v2.1/flavors/3fa85f64-5717-4562-b3fc-2c963f66afa6",
+			endpoint: "http://cloud/RegionOne/compute/v2.1/",
+			want:     "/flavors/{id}",
+		},
+		{
+			name:     "keystone hex ID",
+			url:      "http://cloud/identity/v3/auth/catalog/0123456789abcdef0123456789abcdef",
+			endpoint: "http://cloud/identity/",
+			want:     "/v3/auth/catalog/{id}",
+		},
+		{
+			name: "no endpoint",
+			url:  "http://cloud/identity/v3/auth/tokens",
+			want: "/identity/v3/auth/tokens",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := urlTemplate(u, tt.endpoint); got != tt.want {
+				t.Errorf("urlTemplate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
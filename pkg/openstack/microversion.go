@@ -0,0 +1,210 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxComputeVersion is the highest nova microversion osctl understands.
+	maxComputeVersion = "2.79"
+	// maxLoadBalancerVersion is the highest octavia API version osctl understands.
+	maxLoadBalancerVersion = "2.13"
+)
+
+// apiVersion is an API version in the MAJOR.MINOR format. The zero value means the version is unknown.
+type apiVersion struct {
+	Major int
+	Minor int
+}
+
+func parseAPIVersion(s string) (apiVersion, error) {
+	parts := strings.SplitN(strings.TrimPrefix(s, "v"), ".", 2)
+	if len(parts) != 2 {
+		return apiVersion{}, fmt.Errorf("invalid API version %q, MAJOR.MINOR expected", s)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return apiVersion{}, fmt.Errorf("invalid API version %q, MAJOR.MINOR expected", s)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return apiVersion{}, fmt.Errorf("invalid API version %q, MAJOR.MINOR expected", s)
+	}
+	return apiVersion{Major: major, Minor: minor}, nil
+}
+
+func mustParseAPIVersion(s string) apiVersion {
+	v, err := parseAPIVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (v apiVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+func (v apiVersion) less(other apiVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	return v.Minor < other.Minor
+}
+
+func (v apiVersion) isZero() bool {
+	return v == apiVersion{}
+}
+
+// versionRange is the range of the API versions supported by a service.
+type versionRange struct {
+	min apiVersion
+	max apiVersion
+}
+
+// discoverComputeVersions reads the microversion range from the version document of the nova endpoint.
+func discoverComputeVersions(client *gophercloud.ServiceClient) (versionRange, error) {
+	var body struct {
+		Version struct {
+			Version    string `json:"version"`
+			MinVersion string `json:"min_version"`
+		} `json:"version"`
+	}
+	if _, err := client.Get(client.Endpoint, &body, &gophercloud.RequestOpts{OkCodes: []int{200}}); err != nil {
+		return versionRange{}, err
+	}
+	if body.Version.Version == "" {
+		return versionRange{}, fmt.Errorf("microversions are not supported by the endpoint %s", client.Endpoint)
+	}
+
+	min, err := parseAPIVersion(body.Version.MinVersion)
+	if err != nil {
+		return versionRange{}, err
+	}
+	max, err := parseAPIVersion(body.Version.Version)
+	if err != nil {
+		return versionRange{}, err
+	}
+	return versionRange{min: min, max: max}, nil
+}
+
+// discoverLoadBalancerVersions reads the version list from the root of the octavia endpoint.
+func discoverLoadBalancerVersions(client *gophercloud.ServiceClient) (versionRange, error) {
+	var body struct {
+		Versions []struct {
+			ID string `json:"id"`
+		} `json:"versions"`
+	}
+	if _, err := client.Get(client.Endpoint, &body, &gophercloud.RequestOpts{OkCodes: []int{200, 300}}); err != nil {
+		return versionRange{}, err
+	}
+
+	var r versionRange
+	for _, version := range body.Versions {
+		v, err := parseAPIVersion(version.ID)
+		if err != nil {
+			continue
+		}
+		if r.min.isZero() || v.less(r.min) {
+			r.min = v
+		}
+		if r.max.less(v) {
+			r.max = v
+		}
+	}
+	if r.max.isZero() {
+		return versionRange{}, fmt.Errorf("no API version found at the endpoint %s", client.Endpoint)
+	}
+	return r, nil
+}
+
+// negotiateVersion returns the requested API version if the service supports it, otherwise the highest version
+// supported by both osctl and the service. If the versions can't be discovered, the requested version is trusted and
+// the zero version is returned if none is requested.
+func negotiateVersion(service, requested, maxKnown string, discover func() (versionRange, error)) (apiVersion, error) {
+	var want apiVersion
+	if requested != "" && requested != "latest" {
+		v, err := parseAPIVersion(requested)
+		if err != nil {
+			return apiVersion{}, err
+		}
+		want = v
+	}
+
+	supported, err := discover()
+	if err != nil {
+		log.WithFields(log.Fields{"service": service, "error": err}).Debug("Failed to discover API versions")
+		return want, nil
+	}
+
+	if !want.isZero() {
+		if want.less(supported.min) || supported.max.less(want) {
+			return apiVersion{}, fmt.Errorf("%s API version %s is not supported, the supported range is %s to %s", service, want, supported.min, supported.max)
+		}
+		return want, nil
+	}
+
+	v := mustParseAPIVersion(maxKnown)
+	if supported.max.less(v) {
+		v = supported.max
+	}
+	return v, nil
+}
+
+// negotiateCompute sets the nova microversion of the client.
+func (os *OpenStack) negotiateCompute(ctx context.Context, client *gophercloud.ServiceClient) (apiVersion, error) {
+	v, err := negotiateVersion("nova", os.config.ComputeAPIVersion, maxComputeVersion, func() (versionRange, error) {
+		return discoverComputeVersions(os.withContext(ctx, client))
+	})
+	if err != nil {
+		return apiVersion{}, err
+	}
+	if !v.isZero() {
+		client.Microversion = v.String()
+	}
+	return v, nil
+}
+
+// negotiateLoadBalancer picks the octavia API version. Octavia doesn't have microversion headers, the version only
+// decides which features osctl uses.
+func (os *OpenStack) negotiateLoadBalancer(ctx context.Context, client *gophercloud.ServiceClient) (apiVersion, error) {
+	return negotiateVersion("octavia", os.config.LoadBalancerAPIVersion, maxLoadBalancerVersion, func() (versionRange, error) {
+		return discoverLoadBalancerVersions(os.withContext(ctx, client))
+	})
+}
+
+// requireVersion returns an error if the negotiated API version of the service is lower than min. Nothing is checked
+// if the version is unknown.
+func (os *OpenStack) requireVersion(service, feature, min string) error {
+	os.mu.Lock()
+	v := os.versions[service]
+	os.mu.Unlock()
+
+	if v.isZero() {
+		return nil
+	}
+	if required := mustParseAPIVersion(min); v.less(required) {
+		return fmt.Errorf("%s requires %s API version %s or later, the negotiated version is %s", feature, service, required, v)
+	}
+	return nil
+}
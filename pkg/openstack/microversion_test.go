@@ -0,0 +1,69 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	supported := func(min, max string) func() (versionRange, error) {
+		return func() (versionRange, error) {
+			return versionRange{min: mustParseAPIVersion(min), max: mustParseAPIVersion(max)}, nil
+		}
+	}
+	undiscoverable := func() (versionRange, error) {
+		return versionRange{}, errors.New("no version document")
+	}
+
+	tests := []struct {
+		name      string
+		requested string
+		discover  func() (versionRange, error)
+		want      string
+		wantErr   bool
+	}{
+		{name: "highest known", requested: "", discover: supported("2.0", "2.25"), want: "2.13"},
+		{name: "highest supported", requested: "", discover: supported("2.0", "2.8"), want: "2.8"},
+		{name: "latest", requested: "latest", discover: supported("2.0", "2.8"), want: "2.8"},
+		{name: "requested", requested: "2.5", discover: supported("2.0", "2.8"), want: "2.5"},
+		{name: "requested above the known ones", requested: "2.20", discover: supported("2.0", "2.25"), want: "2.20"},
+		{name: "requested with prefix", requested: "v2.1", discover: supported("2.0", "2.8"), want: "2.1"},
+		{name: "requested too high", requested: "2.10", discover: supported("2.0", "2.8"), wantErr: true},
+		{name: "requested too low", requested: "1.9", discover: supported("2.0", "2.8"), wantErr: true},
+		{name: "invalid", requested: "two", discover: supported("2.0", "2.8"), wantErr: true},
+		{name: "requested without discovery", requested: "2.5", discover: undiscoverable, want: "2.5"},
+		{name: "unknown without discovery", requested: "", discover: undiscoverable, want: "0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := negotiateVersion("octavia", tt.requested, "2.13", tt.discover)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("negotiateVersion() = %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("negotiateVersion() error = %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("negotiateVersion() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,15 +15,21 @@
 package openstack
 
 import (
+	"context"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
 )
 
 // GetPortSecurityGroups get port security group IDs.
-func (os *OpenStack) GetPortSecurityGroups(portID string) ([]string, error) {
-	port, err := ports.Get(os.Neutron, portID).Extract()
+func (os *OpenStack) GetPortSecurityGroups(ctx context.Context, portID string) ([]string, error) {
+	client, err := os.Neutron(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	port, err := ports.Get(client, portID).Extract()
+	if err != nil {
+		return nil, requestError(client, err)
+	}
+
 	return port.SecurityGroups, nil
 }
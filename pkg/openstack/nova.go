@@ -15,14 +15,53 @@
 package openstack
 
 import (
+	"context"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/servergroups"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/pagination"
 )
 
-func (os *OpenStack) GetVM(id string) (*servers.Server, error) {
-	vm, err := servers.Get(os.Nova, id).Extract()
+func (os *OpenStack) GetVM(ctx context.Context, id string) (*servers.Server, error) {
+	client, err := os.Nova(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	vm, err := servers.Get(client, id).Extract()
+	if err != nil {
+		return nil, requestError(client, err)
+	}
+
 	return vm, nil
 }
+
+// GetServerGroupByName returns the server group with the given name, or nil if not found.
+func (os *OpenStack) GetServerGroupByName(ctx context.Context, name string) (*servergroups.ServerGroup, error) {
+	client, err := os.Nova(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *servergroups.ServerGroup
+	err = servergroups.List(client).EachPage(func(page pagination.Page) (bool, error) {
+		actual, err := servergroups.ExtractServerGroups(page)
+		if err != nil {
+			return false, err
+		}
+
+		for i := range actual {
+			if actual[i].Name == name {
+				found = &actual[i]
+				// return false to stop iteration.
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, requestError(client, err)
+	}
+
+	return found, nil
+}
@@ -15,29 +15,39 @@
 package openstack
 
 import (
+	"context"
 	"fmt"
+	"time"
 
-	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/amphorae"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/listeners"
 	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
 	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/pools"
 	"github.com/gophercloud/gophercloud/pagination"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/lingxiankong/openstackcli-go/pkg/tracing"
 	"github.com/lingxiankong/openstackcli-go/pkg/util"
 )
 
-// GetLoadbalancers get all the lbs.
-func (os *OpenStack) GetLoadbalancers(project string) ([]loadbalancers.LoadBalancer, error) {
-	opts := loadbalancers.ListOpts{}
-	if project != "" {
-		opts = loadbalancers.ListOpts{ProjectID: project}
-	}
+// GetLoadbalancers get all the lbs, filtered by project and tags if specified.
+func (os *OpenStack) GetLoadbalancers(ctx context.Context, project string, tags []string) ([]loadbalancers.LoadBalancer, error) {
+	opts := loadbalancers.ListOpts{ProjectID: project, Tags: tags}
 
-	allPages, err := loadbalancers.List(os.Octavia, opts).AllPages()
+	client, err := os.Octavia(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if len(tags) > 0 {
+		if err := os.requireVersion("octavia", "filtering by tags", "2.5"); err != nil {
+			return nil, err
+		}
+	}
+
+	allPages, err := loadbalancers.List(client, opts).AllPages()
+	if err != nil {
+		return nil, requestError(client, err)
+	}
 
 	allLoadbalancers, err := loadbalancers.ExtractLoadBalancers(allPages)
 	if err != nil {
@@ -47,19 +57,46 @@ func (os *OpenStack) GetLoadbalancers(project string) ([]loadbalancers.LoadBalan
 	return allLoadbalancers, nil
 }
 
+// GetLoadBalancer gets the load balancer.
+func (os *OpenStack) GetLoadBalancer(ctx context.Context, id string) (*loadbalancers.LoadBalancer, error) {
+	client, err := os.Octavia(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lb, err := loadbalancers.Get(client, id).Extract()
+	return lb, requestError(client, err)
+}
+
+// GetListener gets the listener.
+func (os *OpenStack) GetListener(ctx context.Context, id string) (*listeners.Listener, error) {
+	client, err := os.Octavia(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := listeners.Get(client, id).Extract()
+	return listener, requestError(client, err)
+}
+
 // GetPools retrives the pools belong to the loadbalancer. If isOrphan is true, only return shared pools in the
 // loadbalancer. If listenerID is specified, return pools belong to that listener.
-func (os *OpenStack) GetPools(lbID string, isOrphan bool, listenerID string) ([]pools.Pool, error) {
+func (os *OpenStack) GetPools(ctx context.Context, lbID string, isOrphan bool, listenerID string) ([]pools.Pool, error) {
 	if isOrphan {
 		listenerID = ""
 	}
 
+	client, err := os.Octavia(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var lbPools []pools.Pool
 
 	opts := pools.ListOpts{
 		LoadbalancerID: lbID,
 	}
-	err := pools.List(os.Octavia, opts).EachPage(func(page pagination.Page) (bool, error) {
+	err = pools.List(client, opts).EachPage(func(page pagination.Page) (bool, error) {
 		ps, err := pools.ExtractPools(page)
 		if err != nil {
 			return false, err
@@ -86,18 +123,23 @@ func (os *OpenStack) GetPools(lbID string, isOrphan bool, listenerID string) ([]
 		return true, nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, requestError(client, err)
 	}
 
 	return lbPools, nil
 }
 
 // GetMembers retrieve all the members of the specified pool
-func (os *OpenStack) GetMembers(poolID string) ([]pools.Member, error) {
+func (os *OpenStack) GetMembers(ctx context.Context, poolID string) ([]pools.Member, error) {
+	client, err := os.Octavia(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var members []pools.Member
 
 	opts := pools.ListMembersOpts{}
-	err := pools.ListMembers(os.Octavia, poolID, opts).EachPage(func(page pagination.Page) (bool, error) {
+	err = pools.ListMembers(client, poolID, opts).EachPage(func(page pagination.Page) (bool, error) {
 		v, err := pools.ExtractMembers(page)
 		if err != nil {
 			return false, err
@@ -106,35 +148,42 @@ func (os *OpenStack) GetMembers(poolID string) ([]pools.Member, error) {
 		return true, nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, requestError(client, err)
 	}
 
 	return members, nil
 }
 
 // FailoverLoadBalancer fails over the specified load balancer and wait for the load balancer to be ACTIVE. Skip if the amphorae of the LB already running with the image.
-func (os *OpenStack) FailoverLoadBalancer(lbID string, image string, timeout int) error {
-	amps, err := os.GetLoadBalancerAmphorae(lbID)
+func (os *OpenStack) FailoverLoadBalancer(ctx context.Context, lbID string, image string, timeout int) (err error) {
+	ctx, span := tracing.Start(ctx, "FailoverLoadBalancer", tracing.String("loadbalancer", lbID), tracing.String("region", os.config.Region))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	amps, err := os.GetLoadBalancerAmphorae(ctx, lbID)
 	if err != nil {
-		return fmt.Errorf("failed to get amphorae for the load balancer %s: %v", lbID, err)
+		return fmt.Errorf("failed to get amphorae for the load balancer %s: %w", lbID, err)
 	}
 
 	if len(amps) == 0 {
-		log.WithFields(log.Fields{"loadbalancer": lbID}).Warn("No amphorae, skip")
+		os.logger().WithFields(log.Fields{"loadbalancer": lbID}).Warn("No amphorae, skip")
+		span.SetAttributes(tracing.String("result", "skipped"))
 		return nil
 	}
 
 	var ampsNeedFix []amphorae.Amphora
 	for _, amp := range amps {
-		vm, err := os.GetVM(amp.ComputeID)
+		vm, err := os.GetVM(ctx, amp.ComputeID)
 		if err != nil {
-			log.WithFields(log.Fields{"loadbalancer": lbID, "amphora": amp.ID}).Warnf("Failed to get VM %s: %v", amp.ComputeID, err)
+			os.logger().WithFields(log.Fields{"loadbalancer": lbID, "amphora": amp.ID, "error": err}).Warnf("Failed to get VM %s", amp.ComputeID)
 			ampsNeedFix = append(ampsNeedFix, amp)
 		} else {
-			log.WithFields(log.Fields{"loadbalancer": lbID, "amphora": amp.ID}).Infof("Nova VM %s", amp.ComputeID)
+			os.logger().WithFields(log.Fields{"loadbalancer": lbID, "amphora": amp.ID}).Infof("Nova VM %s", amp.ComputeID)
 
 			if vm.Image["id"] == image {
-				log.WithFields(log.Fields{"loadbalancer": lbID, "amphora": amp.ID}).Info("Amphora is running with latest image")
+				os.logger().WithFields(log.Fields{"loadbalancer": lbID, "amphora": amp.ID}).Info("Amphora is running with latest image")
 			} else {
 				ampsNeedFix = append(ampsNeedFix, amp)
 			}
@@ -142,56 +191,58 @@ func (os *OpenStack) FailoverLoadBalancer(lbID string, image string, timeout int
 	}
 
 	if len(ampsNeedFix) == 0 {
-		log.WithFields(log.Fields{"loadbalancer": lbID}).Info("Amphorae up to date, skip")
+		os.logger().WithFields(log.Fields{"loadbalancer": lbID}).Info("Amphorae up to date, skip")
+		span.SetAttributes(tracing.String("result", "skipped"))
 		return nil
 	}
 
+	client, err := os.Octavia(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Failover and wait
-	if res := loadbalancers.Failover(os.Octavia, lbID); res.Err != nil {
-		return res.Err
+	if res := loadbalancers.Failover(client, lbID); res.Err != nil {
+		return requestError(client, res.Err)
 	}
-	if err := os.WaitForLoadBalancerState(lbID, "ACTIVE", timeout); err != nil {
+	if err := os.WaitForLoadBalancerState(ctx, lbID, "ACTIVE", timeout); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// WaitForLoadBalancerState will wait until a loadbalancer reaches a given state or ERROR.
-func (os *OpenStack) WaitForLoadBalancerState(lbID, status string, secs int) error {
-	return gophercloud.WaitFor(secs, func() (bool, error) {
-		current, err := loadbalancers.Get(os.Octavia, lbID).Extract()
-		if err != nil {
-			if httpStatus, ok := err.(gophercloud.ErrDefault404); ok {
-				if httpStatus.Actual == 404 {
-					if status == "DELETED" {
-						return true, nil
-					}
-				}
-			}
-			return false, err
-		}
-
-		if current.ProvisioningStatus == "ERROR" {
-			return false, fmt.Errorf("loadbalancer %s goes to ERROR", lbID)
-		}
-
-		if current.ProvisioningStatus == status {
-			return true, nil
-		}
+// WaitForLoadBalancerState will wait until a loadbalancer reaches a given state or ERROR. It gives up after secs seconds
+// or as soon as ctx is done.
+func (os *OpenStack) WaitForLoadBalancerState(ctx context.Context, lbID, status string, secs int) error {
+	ctx, span := tracing.Start(ctx, "WaitForLoadBalancerState", tracing.String("loadbalancer", lbID), tracing.String("status", status))
+	defer span.End()
 
-		return false, nil
+	err := os.Wait(ctx, "loadbalancer", lbID, WaitOpts{
+		Field:   "provisioning_status",
+		Status:  status,
+		Timeout: time.Duration(secs) * time.Second,
 	})
+	span.RecordError(err)
+	return err
 }
 
 // GetLoadBalancerAmphorae return all the amphorae for a load balancer.
-func (os *OpenStack) GetLoadBalancerAmphorae(id string) ([]amphorae.Amphora, error) {
+func (os *OpenStack) GetLoadBalancerAmphorae(ctx context.Context, id string) ([]amphorae.Amphora, error) {
+	client, err := os.Octavia(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.requireVersion("octavia", "amphora API", "2.1"); err != nil {
+		return nil, err
+	}
+
 	listOpts := amphorae.ListOpts{
 		LoadbalancerID: id,
 	}
-	allPages, err := amphorae.List(os.Octavia, listOpts).AllPages()
+	allPages, err := amphorae.List(client, listOpts).AllPages()
 	if err != nil {
-		return nil, err
+		return nil, requestError(client, err)
 	}
 	allAmphorae, err := amphorae.ExtractAmphorae(allPages)
 	if err != nil {
@@ -0,0 +1,165 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
+
+	"github.com/lingxiankong/openstackcli-go/pkg/fakecloud"
+)
+
+func TestGetLoadBalancer(t *testing.T) {
+	server, cfg := newFakeCloud(t, nil)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewOpenStack(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id       string
+		wantName string
+		wantVip  string
+		wantErr  error
+	}{
+		{id: "lb-web", wantName: "web", wantVip: "10.0.0.10"},
+		{id: "lb-db", wantName: "db", wantVip: "10.0.0.11"},
+		{id: "lb-missing", wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			lb, err := client.GetLoadBalancer(ctx, tt.id)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GetLoadBalancer() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetLoadBalancer() error = %v", err)
+			}
+			if lb.Name != tt.wantName || lb.VipAddress != tt.wantVip || lb.ProvisioningStatus != "ACTIVE" {
+				t.Errorf("GetLoadBalancer() = %s %s %s, want %s %s ACTIVE", lb.Name, lb.VipAddress, lb.ProvisioningStatus, tt.wantName, tt.wantVip)
+			}
+		})
+	}
+}
+
+func TestFailoverLoadBalancer(t *testing.T) {
+	f := fakecloud.DefaultFixture()
+	f.FailoverDuration.Duration = 100 * time.Millisecond
+	server, cfg := newFakeCloud(t, f)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewOpenStack(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := client.GetAmphoraImage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// computeIDs returns the VMs of the amphorae and checks they run the image.
+	computeIDs := func(lbID string) []string {
+		amps, err := client.GetLoadBalancerAmphorae(ctx, lbID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, amp := range amps {
+			vm, err := client.GetVM(ctx, amp.ComputeID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if vm.Image["id"] != image {
+				t.Errorf("amphora %s runs image %v, want %s", amp.ID, vm.Image["id"], image)
+			}
+			ids = append(ids, amp.ComputeID)
+		}
+		return ids
+	}
+
+	// The amphorae of lb-web run an old image, they are rebuilt.
+	if err := client.FailoverLoadBalancer(ctx, "lb-web", image, 10); err != nil {
+		t.Fatalf("FailoverLoadBalancer() error = %v", err)
+	}
+	rebuilt := computeIDs("lb-web")
+	if len(rebuilt) != 2 || rebuilt[0] == "vm-amp-web-1" || rebuilt[1] == "vm-amp-web-2" {
+		t.Errorf("amphorae of lb-web run on %v, want new VMs", rebuilt)
+	}
+
+	// They are up to date now, the failover is skipped.
+	if err := client.FailoverLoadBalancer(ctx, "lb-web", image, 10); err != nil {
+		t.Fatalf("FailoverLoadBalancer() error = %v", err)
+	}
+	if ids := computeIDs("lb-web"); ids[0] != rebuilt[0] || ids[1] != rebuilt[1] {
+		t.Errorf("amphorae of lb-web run on %v after a skipped failover, want %v", ids, rebuilt)
+	}
+}
+
+func TestFailoverLoadBalancerStates(t *testing.T) {
+	f := fakecloud.DefaultFixture()
+	f.FailoverDuration.Duration = 100 * time.Millisecond
+	server, cfg := newFakeCloud(t, f)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewOpenStack(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	octavia, err := client.Octavia(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := func() string {
+		lb, err := client.GetLoadBalancer(ctx, "lb-db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lb.ProvisioningStatus
+	}
+
+	if err := loadbalancers.Failover(octavia, "lb-db").ExtractErr(); err != nil {
+		t.Fatalf("Failover() error = %v", err)
+	}
+	if got := status(); got != "PENDING_UPDATE" {
+		t.Errorf("provisioning status = %s after the failover request, want PENDING_UPDATE", got)
+	}
+
+	// The load balancer is immutable until the failover is completed.
+	err = requestError(octavia, loadbalancers.Failover(octavia, "lb-db").ExtractErr())
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("Failover() of a PENDING_UPDATE load balancer error = %v, want %v", err, ErrConflict)
+	}
+
+	if err := client.WaitForLoadBalancerState(ctx, "lb-db", "ACTIVE", 10); err != nil {
+		t.Fatalf("WaitForLoadBalancerState() error = %v", err)
+	}
+	if got := status(); got != "ACTIVE" {
+		t.Errorf("provisioning status = %s after the failover, want ACTIVE", got)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// endpointRegistry maps the endpoints of the service clients to the service names, so that the transport knows
+// which service a request is sent to.
+type endpointRegistry struct {
+	mu       sync.RWMutex
+	prefixes map[string]string
+}
+
+func newEndpointRegistry() *endpointRegistry {
+	return &endpointRegistry{prefixes: make(map[string]string)}
+}
+
+// register records that the URLs under the endpoint belong to the service.
+func (r *endpointRegistry) register(endpoint, service string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prefixes[endpoint] = service
+}
+
+// lookup returns the service of the longest registered endpoint the URL starts with, or "" if there is none.
+func (r *endpointRegistry) lookup(url string) string {
+	service, _ := r.match(url)
+	return service
+}
+
+// match returns the service and the longest registered endpoint the URL starts with.
+func (r *endpointRegistry) match(url string) (service, endpoint string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for prefix, name := range r.prefixes {
+		if strings.HasPrefix(url, prefix) && len(prefix) > len(endpoint) {
+			service, endpoint = name, prefix
+		}
+	}
+	return service, endpoint
+}
+
+// tokenBucket allows rate requests per second on average, with bursts of up to rate requests.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	burst := rate
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// reserve takes a token and returns how long to wait before it can be used.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// rateLimitTransport delays the requests to stay under the global and per-service rate limits.
+type rateLimitTransport struct {
+	next     http.RoundTripper
+	registry *endpointRegistry
+	global   *tokenBucket
+	services map[string]*tokenBucket
+}
+
+// newRateLimitTransport returns next wrapped with the rate limits of the config, or next if there is no limit.
+func newRateLimitTransport(next http.RoundTripper, registry *endpointRegistry, cfg OpenStackConfig) (http.RoundTripper, error) {
+	t := &rateLimitTransport{next: next, registry: registry, services: make(map[string]*tokenBucket)}
+
+	if cfg.MaxQPS < 0 {
+		return nil, fmt.Errorf("invalid max QPS %v", cfg.MaxQPS)
+	}
+	if cfg.MaxQPS > 0 {
+		t.global = newTokenBucket(cfg.MaxQPS)
+	}
+	for service, qps := range cfg.ServiceQPS {
+		if _, ok := serviceTypes[service]; !ok {
+			return nil, fmt.Errorf("unknown service %s in rate limits", service)
+		}
+		if qps < 0 {
+			return nil, fmt.Errorf("invalid max QPS %v for service %s", qps, service)
+		}
+		if qps > 0 {
+			t.services[service] = newTokenBucket(qps)
+		}
+	}
+
+	if t.global == nil && len(t.services) == 0 {
+		return next, nil
+	}
+	return t, nil
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var delay time.Duration
+	if t.global != nil {
+		delay = t.global.reserve()
+	}
+	if bucket, ok := t.services[t.registry.lookup(req.URL.String())]; ok {
+		if d := bucket.reserve(); d > delay {
+			delay = d
+		}
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	return t.next.RoundTrip(req)
+}
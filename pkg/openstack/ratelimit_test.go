@@ -0,0 +1,56 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	tests := []struct {
+		name string
+		rate float64
+		// taken is the number of tokens taken before the checked one.
+		taken int
+		// elapsed is added to the time since the last refill before the checked token is taken.
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{name: "first token", rate: 10, want: 0},
+		{name: "within the burst", rate: 10, taken: 9, want: 0},
+		{name: "over the burst", rate: 10, taken: 10, want: 100 * time.Millisecond},
+		{name: "queued behind the waiting ones", rate: 10, taken: 12, want: 300 * time.Millisecond},
+		{name: "refilled", rate: 10, taken: 10, elapsed: time.Second, want: 0},
+		{name: "refilled up to the burst only", rate: 10, taken: 20, elapsed: time.Minute, want: 0},
+		{name: "burst of one below one per second", rate: 0.5, taken: 1, want: 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTokenBucket(tt.rate)
+			for i := 0; i < tt.taken; i++ {
+				b.reserve()
+			}
+			b.last = b.last.Add(-tt.elapsed)
+
+			// The time passed since the last refill shortens the delay a little.
+			got := b.reserve()
+			if got > tt.want || got < tt.want-10*time.Millisecond {
+				t.Errorf("reserve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
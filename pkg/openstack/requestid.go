@@ -0,0 +1,139 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gophercloud/gophercloud"
+	log "github.com/sirupsen/logrus"
+)
+
+// requestIDHeader is the header of the request ID the services return, e.g. req-9a3d3c3e-....
+const requestIDHeader = "X-Openstack-Request-Id"
+
+// RequestError is an error of an API call with the ID of the request, which can be searched for in the service logs.
+type RequestError struct {
+	RequestID string
+	Err       error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%v (request ID %s)", e.Err, e.RequestID)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// RequestID returns the request ID attached to the error or any error it wraps, or "" if there is none.
+func RequestID(err error) string {
+	var re *RequestError
+	if errors.As(err, &re) {
+		return re.RequestID
+	}
+	return ""
+}
+
+// requestIDRecorder keeps the ID of the last request sent with a context.
+type requestIDRecorder struct {
+	mu   sync.Mutex
+	last string
+}
+
+type requestIDKey struct{}
+
+// withRequestIDRecorder returns a context recording the IDs of the requests sent with it.
+func withRequestIDRecorder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, &requestIDRecorder{})
+}
+
+// lastRequestID returns the ID of the last request sent with the context.
+func lastRequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	rec, ok := ctx.Value(requestIDKey{}).(*requestIDRecorder)
+	if !ok {
+		return ""
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.last
+}
+
+// requestIDTransport records the request ID of every response in the recorder of the request context.
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if rec, ok := req.Context().Value(requestIDKey{}).(*requestIDRecorder); ok {
+		rec.mu.Lock()
+		rec.last = resp.Header.Get(requestIDHeader)
+		rec.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// requestError puts err into its error class and attaches the ID of the last request sent by the client to it.
+func requestError(client *gophercloud.ServiceClient, err error) error {
+	err = classify(err)
+	if err == nil || RequestID(err) != "" {
+		return err
+	}
+	if id := lastRequestID(client.ProviderClient.Context); id != "" {
+		return &RequestError{RequestID: id, Err: err}
+	}
+	return err
+}
+
+// RequestIDHook adds the request_id field to the log entries whose error field carries a request ID.
+type RequestIDHook struct{}
+
+func (RequestIDHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (RequestIDHook) Fire(entry *log.Entry) error {
+	err, ok := entry.Data[log.ErrorKey].(error)
+	if !ok {
+		return nil
+	}
+	if _, exists := entry.Data["request_id"]; exists {
+		return nil
+	}
+	id := RequestID(err)
+	if id == "" {
+		return nil
+	}
+
+	// The map can be shared with other entries, e.g. a logger created by WithFields, so it's copied.
+	data := make(log.Fields, len(entry.Data)+1)
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	data["request_id"] = id
+	entry.Data = data
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultRetryMaxAttempts is the default number of attempts of a request failed with a transient error.
+	DefaultRetryMaxAttempts = 4
+
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+	// maxRetryAfter caps the delay requested by the Retry-After header.
+	maxRetryAfter = time.Minute
+)
+
+// retryStatusCodes are the status codes of the transient failures.
+var retryStatusCodes = map[int]bool{
+	http.StatusConflict:           true,
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// safeMethods are retried on all the transient failures.
+var safeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+}
+
+// idempotentMethods are retried by default as well, but only on rejectedStatusCodes. Some PUTs are actions, e.g. the
+// octavia failover, which may have been accepted behind a 502, 504 or a broken connection and get a 409 when sent
+// again.
+var idempotentMethods = map[string]bool{
+	http.MethodPut:    true,
+	http.MethodDelete: true,
+}
+
+// rejectedStatusCodes are the transient status codes of the requests rejected without being processed, e.g. a 409 of
+// an immutable load balancer.
+var rejectedStatusCodes = map[int]bool{
+	http.StatusConflict:           true,
+	http.StatusTooManyRequests:    true,
+	http.StatusServiceUnavailable: true,
+}
+
+// retryTransport retries the requests failed with connection errors or transient status codes, with exponential
+// backoff and jitter between the attempts.
+type retryTransport struct {
+	next        http.RoundTripper
+	maxAttempts int
+	allMethods  bool
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.maxAttempts <= 1 || !t.retryable(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	// The request of the caller must not be modified, the later attempts send a clone with a new body.
+	attemptReq := req
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(attemptReq)
+
+		if !t.retryFailure(req, resp, err) || attempt >= t.maxAttempts || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		delay := backoff(attempt)
+		fields := log.Fields{"method": req.Method, "url": req.URL.String(), "attempt": attempt}
+		if err != nil {
+			fields["error"] = err
+		} else {
+			fields["status"] = resp.StatusCode
+			fields["request_id"] = resp.Header.Get("X-Openstack-Request-Id")
+			if after, ok := retryAfter(resp); ok {
+				delay = after
+			}
+			// Drain the body so that the connection can be reused.
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		fields["delay"] = delay
+		log.WithFields(fields).Warn("Retrying request")
+
+		if req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable returns true if the request can be sent again.
+func (t *retryTransport) retryable(req *http.Request) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	return t.allMethods || safeMethods[req.Method] || idempotentMethods[req.Method]
+}
+
+// retryFailure returns true if the attempt failed with a transient error the request is retried on.
+func (t *retryTransport) retryFailure(req *http.Request, resp *http.Response, err error) bool {
+	if _, replayMiss := err.(*replayMissError); replayMiss {
+		return false
+	}
+	if t.allMethods || safeMethods[req.Method] {
+		return err != nil || retryStatusCodes[resp.StatusCode]
+	}
+	return err == nil && rejectedStatusCodes[resp.StatusCode]
+}
+
+// backoff returns the delay before the next attempt, a random duration between the half and the full exponential
+// delay.
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay << uint(attempt-1)
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryAfter returns the delay requested by the Retry-After header, in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if secs, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(secs) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = time.Until(date)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay, true
+}
@@ -0,0 +1,187 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: retryBaseDelay},
+		{attempt: 2, max: 2 * retryBaseDelay},
+		{attempt: 4, max: 8 * retryBaseDelay},
+		{attempt: 7, max: retryMaxDelay},
+		// The shift overflows.
+		{attempt: 100, max: retryMaxDelay},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := backoff(tt.attempt); got < tt.max/2 || got > tt.max {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "no header", value: "", want: 0, wantOK: false},
+		{name: "seconds", value: "3", want: 3 * time.Second, wantOK: true},
+		{name: "negative seconds", value: "-5", want: 0, wantOK: true},
+		{name: "capped", value: "3600", want: maxRetryAfter, wantOK: true},
+		{name: "past date", value: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0, wantOK: true},
+		{name: "invalid", value: "soon", want: 0, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.value != "" {
+				resp.Header.Set("Retry-After", tt.value)
+			}
+			got, ok := retryAfter(resp)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter() = %s, %v, want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRetryAfterFutureDate(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(10*time.Second).UTC().Format(http.TimeFormat))
+
+	// The date has a precision of one second.
+	got, ok := retryAfter(resp)
+	if !ok || got < 8*time.Second || got > 10*time.Second {
+		t.Errorf("retryAfter() = %s, %v, want about 10s", got, ok)
+	}
+}
+
+// roundTripFunc is an http.RoundTripper calling the function.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		allMethods bool
+		statuses   []int
+		wantStatus int
+		wantSent   int
+	}{
+		{name: "success", method: http.MethodGet, statuses: []int{200}, wantStatus: 200, wantSent: 1},
+		{name: "transient failure", method: http.MethodGet, statuses: []int{503, 429, 200}, wantStatus: 200, wantSent: 3},
+		{name: "attempts exhausted", method: http.MethodGet, statuses: []int{502, 502, 502}, wantStatus: 502, wantSent: 3},
+		{name: "not transient", method: http.MethodGet, statuses: []int{500, 200}, wantStatus: 500, wantSent: 1},
+		{name: "rejected action", method: http.MethodPut, statuses: []int{409, 503, 202}, wantStatus: 202, wantSent: 3},
+		{name: "action possibly accepted", method: http.MethodPut, statuses: []int{502, 409}, wantStatus: 502, wantSent: 1},
+		{name: "delete possibly accepted", method: http.MethodDelete, statuses: []int{504, 404}, wantStatus: 504, wantSent: 1},
+		{name: "create not retried", method: http.MethodPost, statuses: []int{503, 201}, wantStatus: 503, wantSent: 1},
+		{name: "all methods", method: http.MethodPost, allMethods: true, statuses: []int{503, 201}, wantStatus: 201, wantSent: 2},
+		{name: "all methods on all failures", method: http.MethodPut, allMethods: true, statuses: []int{502, 202}, wantStatus: 202, wantSent: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []string
+			next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				var body []byte
+				if req.Body != nil {
+					body, _ = ioutil.ReadAll(req.Body)
+				}
+				sent = append(sent, string(body))
+				header := http.Header{}
+				// Don't wait between the attempts.
+				header.Set("Retry-After", "0")
+				return &http.Response{
+					StatusCode: tt.statuses[len(sent)-1],
+					Header:     header,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}, nil
+			})
+			transport := &retryTransport{next: next, maxAttempts: 3, allMethods: tt.allMethods}
+
+			req, err := http.NewRequest(tt.method, "http://127.0.0.1/v2/lbaas/loadbalancers", bytes.NewReader([]byte(`{"name":"web"}`)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus || len(sent) != tt.wantSent {
+				t.Errorf("RoundTrip() = %d after %d attempts, want %d after %d attempts", resp.StatusCode, len(sent), tt.wantStatus, tt.wantSent)
+			}
+			for i, body := range sent {
+				if body != `{"name":"web"}` {
+					t.Errorf("attempt %d sent body %q", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransportConnectionError(t *testing.T) {
+	tests := []struct {
+		method       string
+		wantAttempts int
+	}{
+		{method: http.MethodGet, wantAttempts: 2},
+		// The connection may have broken after the action was accepted.
+		{method: http.MethodPut, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			var attempts int
+			next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts == 1 {
+					return nil, errors.New("connection reset by peer")
+				}
+				return &http.Response{StatusCode: 200, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+			})
+			transport := &retryTransport{next: next, maxAttempts: 2}
+
+			req, _ := http.NewRequest(tt.method, "http://127.0.0.1/v2/lbaas/loadbalancers", nil)
+			_, err := transport.RoundTrip(req)
+			if attempts != tt.wantAttempts || (err == nil) != (tt.wantAttempts == 2) {
+				t.Errorf("RoundTrip() error = %v after %d attempts, want %d attempts", err, attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
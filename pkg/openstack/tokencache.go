@@ -0,0 +1,236 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
+	log "github.com/sirupsen/logrus"
+)
+
+// tokenExpiryMargin is how long before its expiry a cached token stops being reused.
+const tokenExpiryMargin = 5 * time.Minute
+
+// cachedToken is the on-disk format of a cached keystone token.
+type cachedToken struct {
+	ID        string      `json:"id"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	Body      interface{} `json:"body"`
+}
+
+// tokenCacheDir returns the directory of the token cache files.
+func tokenCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "osctl", "tokens"), nil
+}
+
+// tokenCacheKey identifies the credentials a token was issued for.
+func (cfg OpenStackConfig) tokenCacheKey() string {
+	user := cfg.Username
+	if cfg.UseApplicationCredential() {
+		user = strings.Join([]string{cfg.ApplicationCredentialID, cfg.ApplicationCredentialName, cfg.Username}, "/")
+	}
+	user = strings.Join([]string{user, cfg.UserDomainID, cfg.UserDomainName}, "/")
+	project := strings.Join([]string{cfg.ProjectID, cfg.ProjectName, cfg.ProjectDomainID, cfg.ProjectDomainName,
+		cfg.DomainID, cfg.DomainName, cfg.SystemScope}, "/")
+	parts := []string{cfg.AuthURL, user, project, cfg.Region}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func (cfg OpenStackConfig) tokenCacheFile() (string, error) {
+	dir, err := tokenCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, cfg.tokenCacheKey()+".json"), nil
+}
+
+// loadCachedToken returns the cached token for the config, or nil if there is no usable one.
+func (cfg OpenStackConfig) loadCachedToken() *tokens.CreateResult {
+	file, err := cfg.tokenCacheFile()
+	if err != nil {
+		return nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		log.WithFields(log.Fields{"file": file}).Warn("Token cache file is accessible by other users, ignored")
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var cached cachedToken
+	if err := json.Unmarshal(data, &cached); err != nil {
+		log.WithFields(log.Fields{"file": file, "error": err}).Debug("Invalid token cache file")
+		return nil
+	}
+	if time.Until(cached.ExpiresAt) < tokenExpiryMargin {
+		log.WithFields(log.Fields{"expires_at": cached.ExpiresAt}).Debug("Cached token is about to expire")
+		return nil
+	}
+
+	var result tokens.CreateResult
+	result.Body = cached.Body
+	result.Header = http.Header{}
+	result.Header.Set("X-Subject-Token", cached.ID)
+
+	return &result
+}
+
+// saveCachedToken writes the auth result of the provider client into the token cache.
+func (cfg OpenStackConfig) saveCachedToken(provider *gophercloud.ProviderClient) error {
+	result, ok := provider.GetAuthResult().(tokens.CreateResult)
+	if !ok {
+		return fmt.Errorf("unexpected auth result type %T", provider.GetAuthResult())
+	}
+
+	id, err := result.ExtractTokenID()
+	if err != nil {
+		return err
+	}
+	token, err := result.ExtractToken()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cachedToken{ID: id, ExpiresAt: token.ExpiresAt, Body: result.Body})
+	if err != nil {
+		return err
+	}
+
+	file, err := cfg.tokenCacheFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that concurrent osctl processes never read a partial file.
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".token-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+// RemoveCachedToken removes the cached token of the config.
+func (cfg OpenStackConfig) RemoveCachedToken() error {
+	file, err := cfg.tokenCacheFile()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// PurgeTokenCache removes all the cached tokens.
+func PurgeTokenCache() error {
+	dir, err := tokenCacheDir()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// authenticateWithCache authenticates the provider client with a cached token if there is one, otherwise a new token
+// is requested and cached. The re-authentication on 401 refreshes the cache as well.
+func authenticateWithCache(provider *gophercloud.ProviderClient, cfg OpenStackConfig) error {
+	authenticate := func(client *gophercloud.ProviderClient) error {
+		// The password is only resolved when a new token is needed.
+		if err := cfg.resolvePassword(); err != nil {
+			return err
+		}
+		opts := cfg.ToAuthOptions()
+		opts.AllowReauth = false
+		if err := openstack.AuthenticateV3(client, opts, gophercloud.EndpointOpts{}); err != nil {
+			return err
+		}
+		if err := cfg.saveCachedToken(client); err != nil {
+			log.WithFields(log.Fields{"error": err}).Warn("Failed to cache token")
+		}
+		return nil
+	}
+
+	if result := cfg.loadCachedToken(); result != nil {
+		catalog, err := result.ExtractServiceCatalog()
+		if err != nil {
+			return err
+		}
+		if err := provider.SetTokenAndAuthResult(*result); err != nil {
+			return err
+		}
+		provider.EndpointLocator = func(opts gophercloud.EndpointOpts) (string, error) {
+			return openstack.V3EndpointURL(catalog, opts)
+		}
+		log.Debug("Using cached token")
+	} else if err := authenticate(provider); err != nil {
+		return err
+	}
+
+	provider.ReauthFunc = func() error {
+		// Authenticate with a throw-away copy of the provider client so that the expired token is not sent.
+		tac := *provider
+		tac.SetThrowaway(true)
+		tac.ReauthFunc = nil
+		tac.SetTokenAndAuthResult(nil)
+		if err := authenticate(&tac); err != nil {
+			return err
+		}
+		provider.CopyTokenFrom(&tac)
+		return nil
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lingxiankong/openstackcli-go/pkg/tracing"
+)
+
+// tracingTransport records a client span for every HTTP request, as a child of the span in the request context.
+type tracingTransport struct {
+	next     http.RoundTripper
+	registry *endpointRegistry
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !tracing.Enabled() {
+		return t.next.RoundTrip(req)
+	}
+
+	service, endpoint := t.registry.match(req.URL.String())
+	if service == "" {
+		service = "unknown"
+	}
+	ctx, span := tracing.StartClient(req.Context(), fmt.Sprintf("%s %s", req.Method, urlTemplate(req.URL, endpoint)),
+		tracing.String("openstack.service", service),
+		tracing.String("http.method", req.Method),
+		tracing.String("http.url", req.URL.String()),
+	)
+	defer span.End()
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes(tracing.Int("http.status_code", resp.StatusCode))
+	if id := resp.Header.Get(requestIDHeader); id != "" {
+		span.SetAttributes(tracing.String("openstack.request_id", id))
+	}
+	if resp.StatusCode >= 400 {
+		span.RecordError(fmt.Errorf("%s", resp.Status))
+	}
+	return resp, nil
+}
@@ -0,0 +1,120 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// newTLSConfig builds the TLS configuration from the CA bundle, client certificate and insecure settings.
+func newTLSConfig(cfg OpenStackConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if cfg.CACert != "" {
+		pem, err := ioutil.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificate found in CA bundle %s", cfg.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		if cfg.ClientCert == "" || cfg.ClientKey == "" {
+			return nil, fmt.Errorf("both client certificate and key are required")
+		}
+
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.Insecure {
+		log.Warn("TLS certificate verification is disabled, the connections to OpenStack are not secure")
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	return tlsConfig, nil
+}
+
+// newHTTPClient returns the HTTP client used by the provider client. The registry tells the transport which service a
+// request is sent to.
+func newHTTPClient(cfg OpenStackConfig, registry *endpointRegistry) (http.Client, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return http.Client{}, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	var base http.RoundTripper = transport
+	switch {
+	case cfg.Record != "" && cfg.Replay != "":
+		return http.Client{}, fmt.Errorf("record and replay can't be used together")
+	case cfg.Record != "":
+		if base, err = newRecordTransport(transport, cfg.Record); err != nil {
+			return http.Client{}, err
+		}
+	case cfg.Replay != "":
+		if base, err = newReplayTransport(cfg.Replay, cfg.ReplayMode); err != nil {
+			return http.Client{}, err
+		}
+	}
+
+	if cfg.DebugHTTP || cfg.DebugHTTPBodies || cfg.DebugHTTPFile != "" {
+		logger, err := traceLogger(cfg.DebugHTTPFile)
+		if err != nil {
+			return http.Client{}, err
+		}
+		base = &debugTransport{next: base, logger: logger, registry: registry, bodies: cfg.DebugHTTPBodies}
+	}
+
+	if cfg.Metrics {
+		base = &metricsTransport{next: base, registry: registry}
+	}
+	base = &tracingTransport{next: &requestIDTransport{next: base}, registry: registry}
+
+	limited, err := newRateLimitTransport(base, registry, cfg)
+	if err != nil {
+		return http.Client{}, err
+	}
+
+	maxAttempts := cfg.RetryMaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultRetryMaxAttempts
+	}
+
+	return http.Client{Transport: &retryTransport{
+		next:        limited,
+		maxAttempts: maxAttempts,
+		allMethods:  cfg.RetryAllMethods,
+	}}, nil
+}
@@ -0,0 +1,343 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/amphorae"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/pools"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lingxiankong/openstackcli-go/pkg/util"
+)
+
+const (
+	// DefaultWaitInterval is the default delay between the first polls of a wait.
+	DefaultWaitInterval = time.Second
+	// DefaultWaitMaxInterval is the default maximum delay between the polls.
+	DefaultWaitMaxInterval = 15 * time.Second
+	// DefaultWaitBackoff is the default factor the delay grows by after each poll.
+	DefaultWaitBackoff = 1.5
+	// DefaultWaitProgressInterval is how often the state is logged by default while waiting.
+	DefaultWaitProgressInterval = 30 * time.Second
+)
+
+// WaitOpts describes the state to wait for and how to poll the resource.
+type WaitOpts struct {
+	// Field is the status field to check, e.g. provisioning_status, and Status is the value to wait for. The default
+	// field and status of the resource type are used if empty. Waiting for DELETED succeeds once the resource is gone.
+	Field  string
+	Status string
+	// OperatingStatus is the operating status the octavia resources have to reach as well, not checked if empty.
+	OperatingStatus string
+	// FailStates are the values of Field which end the wait with an error. If nil, the default fail states of the
+	// resource type are checked on its default field whatever Field is, e.g. ERROR for the provisioning status.
+	FailStates []string
+	// failField is the field FailStates are checked on.
+	failField string
+
+	// Interval is the delay between the first polls, it's multiplied by Backoff after each poll up to MaxInterval.
+	Interval    time.Duration
+	Backoff     float64
+	MaxInterval time.Duration
+	// Timeout is the maximum duration of the wait, the wait only ends with the context if it's zero.
+	Timeout time.Duration
+	// ProgressInterval is how often the current state is logged while waiting.
+	ProgressInterval time.Duration
+}
+
+// resourceStatus holds the status fields of the resources Wait can poll.
+type resourceStatus struct {
+	ProvisioningStatus string `json:"provisioning_status"`
+	OperatingStatus    string `json:"operating_status"`
+	Status             string `json:"status"`
+}
+
+func (s resourceStatus) field(name string) string {
+	switch name {
+	case "provisioning_status":
+		return s.ProvisioningStatus
+	case "operating_status":
+		return s.OperatingStatus
+	default:
+		return s.Status
+	}
+}
+
+// waitResource describes how to wait for a resource type.
+type waitResource struct {
+	// fields are the status fields of the resource type, the first one is waited for by default.
+	fields []string
+	// status is the status waited for by default.
+	status string
+	// failStates are the values of the first field which end the wait by default.
+	failStates []string
+	get        func(os *OpenStack, ctx context.Context, id string) (resourceStatus, error)
+}
+
+var waitResources = map[string]waitResource{
+	"loadbalancer": {
+		fields:     []string{"provisioning_status", "operating_status"},
+		status:     "ACTIVE",
+		failStates: []string{"ERROR"},
+		get: func(os *OpenStack, ctx context.Context, id string) (s resourceStatus, err error) {
+			client, err := os.Octavia(ctx)
+			if err != nil {
+				return s, err
+			}
+			err = loadbalancers.Get(client, id).ExtractIntoStructPtr(&s, "loadbalancer")
+			return s, requestError(client, err)
+		},
+	},
+	"listener": {
+		fields:     []string{"provisioning_status", "operating_status"},
+		status:     "ACTIVE",
+		failStates: []string{"ERROR"},
+		get: func(os *OpenStack, ctx context.Context, id string) (s resourceStatus, err error) {
+			client, err := os.Octavia(ctx)
+			if err != nil {
+				return s, err
+			}
+			err = listeners.Get(client, id).ExtractIntoStructPtr(&s, "listener")
+			return s, requestError(client, err)
+		},
+	},
+	"pool": {
+		fields:     []string{"provisioning_status", "operating_status"},
+		status:     "ACTIVE",
+		failStates: []string{"ERROR"},
+		get: func(os *OpenStack, ctx context.Context, id string) (s resourceStatus, err error) {
+			client, err := os.Octavia(ctx)
+			if err != nil {
+				return s, err
+			}
+			err = pools.Get(client, id).ExtractIntoStructPtr(&s, "pool")
+			return s, requestError(client, err)
+		},
+	},
+	"amphora": {
+		fields:     []string{"status"},
+		status:     "ALLOCATED",
+		failStates: []string{"ERROR"},
+		get: func(os *OpenStack, ctx context.Context, id string) (s resourceStatus, err error) {
+			client, err := os.Octavia(ctx)
+			if err != nil {
+				return s, err
+			}
+			if err := os.requireVersion("octavia", "amphora API", "2.1"); err != nil {
+				return s, err
+			}
+			err = amphorae.Get(client, id).ExtractIntoStructPtr(&s, "amphora")
+			return s, requestError(client, err)
+		},
+	},
+	"server": {
+		fields:     []string{"status"},
+		status:     "ACTIVE",
+		failStates: []string{"ERROR"},
+		get: func(os *OpenStack, ctx context.Context, id string) (s resourceStatus, err error) {
+			client, err := os.Nova(ctx)
+			if err != nil {
+				return s, err
+			}
+			err = servers.Get(client, id).ExtractIntoStructPtr(&s, "server")
+			return s, requestError(client, err)
+		},
+	},
+}
+
+// WaitResourceTypes returns the resource types supported by Wait.
+func WaitResourceTypes() []string {
+	var types []string
+	for t := range waitResources {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Validate checks the options and the resource type without calling the cloud, Wait returns the same errors.
+func (opts WaitOpts) Validate(resourceType string) error {
+	_, _, err := opts.resolve(resourceType)
+	return err
+}
+
+// resolve returns the options with the defaults filled in and how to wait for the resource type.
+func (opts WaitOpts) resolve(resourceType string) (WaitOpts, waitResource, error) {
+	res, ok := waitResources[resourceType]
+	if !ok {
+		return opts, res, fmt.Errorf("unknown resource type %s, expected one of %s", resourceType, strings.Join(WaitResourceTypes(), ", "))
+	}
+	opts, err := opts.withDefaults(resourceType, res)
+	return opts, res, err
+}
+
+// withDefaults validates the options for the resource type and fills in the defaults.
+func (opts WaitOpts) withDefaults(resourceType string, res waitResource) (WaitOpts, error) {
+	if opts.Field == "" {
+		opts.Field = res.fields[0]
+	}
+	if !util.FindString(opts.Field, res.fields) {
+		return opts, fmt.Errorf("%s has no field %s, expected one of %s", resourceType, opts.Field, strings.Join(res.fields, ", "))
+	}
+	if opts.Status == "" {
+		opts.Status = res.status
+	}
+	if opts.OperatingStatus != "" && !util.FindString("operating_status", res.fields) {
+		return opts, fmt.Errorf("%s has no operating status", resourceType)
+	}
+	opts.failField = opts.Field
+	if opts.FailStates == nil {
+		opts.FailStates = res.failStates
+		opts.failField = res.fields[0]
+	}
+	for _, state := range opts.FailStates {
+		if state == "" {
+			return opts, fmt.Errorf("empty fail state")
+		}
+		if opts.failField == opts.Field && state == opts.Status {
+			return opts, fmt.Errorf("fail state %s is the status waited for", state)
+		}
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWaitInterval
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = DefaultWaitMaxInterval
+	}
+	if opts.MaxInterval < opts.Interval {
+		opts.MaxInterval = opts.Interval
+	}
+	if opts.Backoff == 0 {
+		opts.Backoff = DefaultWaitBackoff
+	}
+	if opts.Backoff < 1 {
+		return opts, fmt.Errorf("invalid wait backoff %v, it can't be less than 1", opts.Backoff)
+	}
+	if opts.Timeout < 0 {
+		return opts, fmt.Errorf("invalid wait timeout %s", opts.Timeout)
+	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = DefaultWaitProgressInterval
+	}
+
+	return opts, nil
+}
+
+// condition describes the state waited for, e.g. provisioning_status=ACTIVE.
+func (opts WaitOpts) condition() string {
+	c := fmt.Sprintf("%s=%s", opts.Field, opts.Status)
+	if opts.OperatingStatus != "" {
+		c += fmt.Sprintf(", operating_status=%s", opts.OperatingStatus)
+	}
+	return c
+}
+
+func (opts WaitOpts) reached(s resourceStatus) bool {
+	return s.field(opts.Field) == opts.Status && (opts.OperatingStatus == "" || s.OperatingStatus == opts.OperatingStatus)
+}
+
+// Wait polls the resource of the given type until it reaches the state in opts. It returns an error if the resource
+// goes to one of the fail states, the timeout expires or ctx is done.
+func (os *OpenStack) Wait(ctx context.Context, resourceType, id string, opts WaitOpts) error {
+	opts, res, err := opts.resolve(resourceType)
+	if err != nil {
+		return err
+	}
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	logger := os.logger().WithFields(log.Fields{resourceType: id, "condition": opts.condition()})
+	start := time.Now()
+	lastProgress := start
+	interval := opts.Interval
+	var current resourceStatus
+
+	for {
+		s, err := res.get(os, ctx, id)
+		if err != nil {
+			if errors.As(err, &gophercloud.ErrDefault404{}) && opts.Status == "DELETED" {
+				logger.Debug("Resource deleted")
+				return nil
+			}
+			if ctx.Err() == nil {
+				return err
+			}
+		} else {
+			if opts.reached(s) {
+				logger.WithFields(log.Fields{"elapsed": time.Since(start).Round(time.Second)}).Debug("Condition met")
+				return nil
+			}
+			if value := s.field(opts.failField); util.FindString(value, opts.FailStates) {
+				return fmt.Errorf("%s %s goes to %s %s", resourceType, id, opts.failField, value)
+			}
+			if s != current {
+				logger.WithFields(statusFields(s, res.fields)).Debug("Status changed")
+				current = s
+			}
+		}
+
+		if time.Since(lastProgress) >= opts.ProgressInterval {
+			fields := statusFields(current, res.fields)
+			fields["elapsed"] = time.Since(start).Round(time.Second)
+			logger.WithFields(fields).Info("Still waiting")
+			lastProgress = time.Now()
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if ctx.Err() == context.DeadlineExceeded {
+				value := current.field(opts.Field)
+				if value == "" {
+					value = "unknown"
+				}
+				return fmt.Errorf("%w after %s waiting for %s %s to be %s, the current %s is %s", ErrTimeout, time.Since(start).Round(time.Second), resourceType, id, opts.condition(), opts.Field, value)
+			}
+			return fmt.Errorf("stopped waiting for %s %s to be %s: %w", resourceType, id, opts.condition(), ctx.Err())
+		case <-timer.C:
+		}
+
+		interval = time.Duration(float64(interval) * opts.Backoff)
+		if interval > opts.MaxInterval {
+			interval = opts.MaxInterval
+		}
+	}
+}
+
+// statusFields returns the log fields of the status.
+func statusFields(s resourceStatus, fields []string) log.Fields {
+	f := log.Fields{}
+	for _, name := range fields {
+		f[name] = s.field(name)
+	}
+	return f
+}
@@ -0,0 +1,125 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWaitOptsWithDefaults(t *testing.T) {
+	defaults := func(opts WaitOpts) WaitOpts {
+		opts.Interval = DefaultWaitInterval
+		opts.MaxInterval = DefaultWaitMaxInterval
+		opts.Backoff = DefaultWaitBackoff
+		opts.ProgressInterval = DefaultWaitProgressInterval
+		return opts
+	}
+
+	tests := []struct {
+		name         string
+		resourceType string
+		opts         WaitOpts
+		want         WaitOpts
+		wantErr      bool
+	}{
+		{
+			name:         "default field",
+			resourceType: "loadbalancer",
+			want: defaults(WaitOpts{
+				Field: "provisioning_status", Status: "ACTIVE", FailStates: []string{"ERROR"}, failField: "provisioning_status",
+			}),
+		},
+		{
+			name:         "other field fails on the default one",
+			resourceType: "loadbalancer",
+			opts:         WaitOpts{Field: "operating_status", Status: "ONLINE"},
+			want: defaults(WaitOpts{
+				Field: "operating_status", Status: "ONLINE", FailStates: []string{"ERROR"}, failField: "provisioning_status",
+			}),
+		},
+		{
+			name:         "fail states of the field",
+			resourceType: "loadbalancer",
+			opts:         WaitOpts{Field: "operating_status", Status: "ONLINE", FailStates: []string{"DEGRADED"}},
+			want: defaults(WaitOpts{
+				Field: "operating_status", Status: "ONLINE", FailStates: []string{"DEGRADED"}, failField: "operating_status",
+			}),
+		},
+		{
+			name:         "no fail states",
+			resourceType: "server",
+			opts:         WaitOpts{FailStates: []string{}},
+			want:         defaults(WaitOpts{Field: "status", Status: "ACTIVE", FailStates: []string{}, failField: "status"}),
+		},
+		{
+			name:         "polling options",
+			resourceType: "amphora",
+			opts:         WaitOpts{Interval: 20 * time.Second, Backoff: 1, Timeout: time.Minute, ProgressInterval: time.Second},
+			want: WaitOpts{
+				Field: "status", Status: "ALLOCATED", FailStates: []string{"ERROR"}, failField: "status",
+				Interval: 20 * time.Second, MaxInterval: 20 * time.Second, Backoff: 1, Timeout: time.Minute, ProgressInterval: time.Second,
+			},
+		},
+		{name: "unknown field", resourceType: "pool", opts: WaitOpts{Field: "status"}, wantErr: true},
+		{name: "empty fail state", resourceType: "pool", opts: WaitOpts{FailStates: []string{""}}, wantErr: true},
+		{name: "fail state waited for", resourceType: "server", opts: WaitOpts{Status: "ERROR", FailStates: []string{"ERROR"}}, wantErr: true},
+		{
+			name:         "fail state of the default field waited for",
+			resourceType: "loadbalancer",
+			opts:         WaitOpts{Field: "operating_status", Status: "ERROR"},
+			want: defaults(WaitOpts{
+				Field: "operating_status", Status: "ERROR", FailStates: []string{"ERROR"}, failField: "provisioning_status",
+			}),
+		},
+		{name: "no operating status", resourceType: "amphora", opts: WaitOpts{OperatingStatus: "ONLINE"}, wantErr: true},
+		{name: "invalid backoff", resourceType: "listener", opts: WaitOpts{Backoff: 0.5}, wantErr: true},
+		{name: "invalid timeout", resourceType: "listener", opts: WaitOpts{Timeout: -time.Second}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := waitResources[tt.resourceType]
+			fields := append([]string(nil), res.fields...)
+
+			got, err := tt.opts.withDefaults(tt.resourceType, res)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("withDefaults() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("withDefaults() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(res.fields, fields) {
+				t.Errorf("withDefaults() changed the fields of %s to %v", tt.resourceType, res.fields)
+			}
+		})
+	}
+}
+
+func TestWaitOptsValidate(t *testing.T) {
+	if err := (WaitOpts{}).Validate("loadbalancer"); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := (WaitOpts{}).Validate("volume"); err == nil {
+		t.Error("Validate() error = nil, want an error for an unknown resource type")
+	}
+}
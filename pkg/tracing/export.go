@@ -0,0 +1,200 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter sends a batch of spans encoded as an OTLP-JSON ExportTraceServiceRequest.
+type Exporter interface {
+	Export(data []byte) error
+}
+
+// The OTLP-JSON types, see opentelemetry-proto. The IDs are hex encoded and the 64 bit integers are strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// statusError is the OTLP status code of a failed span.
+const statusError = 2
+
+func otlpAttributes(attrs []Attribute) []otlpAttribute {
+	var out []otlpAttribute
+	for _, a := range attrs {
+		var v otlpValue
+		switch value := a.Value.(type) {
+		case string:
+			v.StringValue = &value
+		case bool:
+			v.BoolValue = &value
+		case int:
+			s := strconv.Itoa(value)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(value, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &value
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		out = append(out, otlpAttribute{Key: a.Key, Value: v})
+	}
+	return out
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// encode returns the spans as an OTLP-JSON ExportTraceServiceRequest.
+func encode(spans []*Span) ([]byte, error) {
+	var out []otlpSpan
+	for _, s := range spans {
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: unixNano(s.start),
+			EndTimeUnixNano:   unixNano(s.end),
+			Attributes:        otlpAttributes(s.attributes),
+		}
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != "" {
+			span.Status = &otlpStatus{Code: statusError, Message: s.err}
+		}
+		s.mu.Unlock()
+		out = append(out, span)
+	}
+
+	return json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes([]Attribute{String("service.name", "osctl")})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "osctl"}, Spans: out}},
+	}}})
+}
+
+// fileExporter appends every batch to the file as a line of JSON, the format of the file exporter of the
+// OpenTelemetry collector.
+type fileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileExporter returns an exporter appending the spans to the file.
+func NewFileExporter(path string) (Exporter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %v", err)
+	}
+	return &fileExporter{file: f}, nil
+}
+
+func (e *fileExporter) Export(data []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err := e.file.Write(append(data, '\n'))
+	return err
+}
+
+// httpExporter posts the spans to an OTLP/HTTP receiver, e.g. the collector or Jaeger.
+type httpExporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPExporter returns an exporter sending the spans to the OTLP/HTTP endpoint. The /v1/traces path is added to
+// the endpoint unless it's already there.
+func NewHTTPExporter(endpoint string) (Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid trace endpoint %q, expected an http or https URL", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, "/v1/traces") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/traces"
+	}
+	return &httpExporter{url: u.String(), client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (e *httpExporter) Export(data []byte) error {
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("failed to export spans to %s: %s %s", e.url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
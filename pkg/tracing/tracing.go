@@ -0,0 +1,233 @@
+// Copyright © 2020 Lingxian Kong <anlin.kong@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing records the spans of an osctl run and exports them in the OTLP-JSON format, so a run can be looked
+// at in Jaeger or any other OpenTelemetry backend. Tracing is off until Init is called, the spans are nil then and all
+// their methods do nothing.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Span kinds of OTLP.
+const (
+	kindInternal = 1
+	kindClient   = 3
+)
+
+// Attribute is a key value pair of a span, the value is a string, bool, int, int64 or float64.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is an operation of the run, e.g. the command, a worker or an HTTP request.
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes []Attribute
+	err        string
+	ended      bool
+}
+
+type spanKey struct{}
+
+var tracer struct {
+	mu       sync.Mutex
+	exporter Exporter
+	// ended are the spans waiting to be exported.
+	ended []*Span
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// Init enables tracing, the ended spans are exported every interval and by Shutdown.
+func Init(exporter Exporter, interval time.Duration) {
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+
+	tracer.exporter = exporter
+	stop, done := make(chan struct{}), make(chan struct{})
+	tracer.stop, tracer.done = stop, done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := flush(); err != nil {
+					log.WithFields(log.Fields{"error": err}).Warn("Failed to export trace spans")
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown exports the remaining spans and disables tracing.
+func Shutdown() error {
+	tracer.mu.Lock()
+	// The first call clears the stop channel, the later ones, e.g. by a forced
+	// exit while the first one is exporting, have nothing to do.
+	if tracer.exporter == nil || tracer.stop == nil {
+		tracer.mu.Unlock()
+		return nil
+	}
+	stop, done := tracer.stop, tracer.done
+	tracer.stop = nil
+	close(stop)
+	tracer.mu.Unlock()
+
+	<-done
+	err := flush()
+
+	tracer.mu.Lock()
+	tracer.exporter = nil
+	tracer.mu.Unlock()
+	return err
+}
+
+// Enabled returns true if tracing is enabled.
+func Enabled() bool {
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	return tracer.exporter != nil
+}
+
+// Start starts a span as a child of the span in ctx, or a new trace if there is none. It returns ctx unchanged and a
+// nil span if tracing is disabled.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return start(ctx, name, kindInternal, attrs)
+}
+
+// StartClient starts a span of a request to a remote service.
+func StartClient(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return start(ctx, name, kindClient, attrs)
+}
+
+func start(ctx context.Context, name string, kind int, attrs []Attribute) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+
+	s := &Span{name: name, kind: kind, start: time.Now(), attributes: attrs}
+	if parent := FromContext(ctx); parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// FromContext returns the current span of ctx, or nil.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attrs...)
+}
+
+// RecordError marks the span as failed with the error, nothing is done if err is nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err.Error()
+}
+
+// End ends the span and queues it for export, only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	if tracer.exporter != nil {
+		tracer.ended = append(tracer.ended, s)
+	}
+}
+
+// TraceID returns the trace ID of the span in hex, or "" for a nil span.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", s.traceID)
+}
+
+// flush exports the ended spans.
+func flush() error {
+	tracer.mu.Lock()
+	spans, exporter := tracer.ended, tracer.exporter
+	tracer.ended = nil
+	tracer.mu.Unlock()
+
+	if len(spans) == 0 || exporter == nil {
+		return nil
+	}
+	data, err := encode(spans)
+	if err != nil {
+		return err
+	}
+	return exporter.Export(data)
+}
@@ -1,18 +1,15 @@
 package util
 
-import (
-	"sort"
-)
-
+// FindString returns true if a is in the list. The list is not sorted in place, the callers rely on its order.
 func FindString(a string, list []string) bool {
-	if a == "" || len(list) == 0 {
+	if a == "" {
 		return false
 	}
 
-	sort.Strings(list)
-	index := sort.SearchStrings(list, a)
-	if index < len(list) && list[index] == a {
-		return true
+	for _, s := range list {
+		if s == a {
+			return true
+		}
 	}
 	return false
 }